@@ -1,6 +1,37 @@
 package auth
 
-import "github.com/mattmeyers/heimdall/crypto"
+import (
+	"errors"
+
+	"github.com/mattmeyers/heimdall/crypto"
+)
+
+// AuthCodeSettings are the available configuration values for issuing auth codes.
+type AuthCodeSettings struct {
+	// Lifespan is the number of seconds an auth code can be exchanged for tokens.
+	Lifespan int
+}
+
+func (s AuthCodeSettings) validate() error {
+	if s.Lifespan <= 0 {
+		return errors.New("auth code lifetime must be a positive integer")
+	}
+
+	return nil
+}
+
+// AuthCodeRequest holds the parameters a client sends to the authorization endpoint when
+// initiating the authorization code flow.
+type AuthCodeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURL         string
+	State               string
+	Scope               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
 
 func generateAuthCode() (string, error) {
 	return crypto.GenerateRandHexString(32)
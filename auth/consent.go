@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/mattmeyers/heimdall/crypto"
+	"github.com/mattmeyers/heimdall/store"
+)
+
+// ErrAccessDenied is returned when the user declines to grant a client access.
+var ErrAccessDenied = newError(AccessDenied, "the user denied the request")
+
+// consentRequestLifespan is how long a user has to answer the consent screen.
+const consentRequestLifespan = 10 * time.Minute
+
+// AuthCodeGrant is the result of signing in during the authorization code flow. If the user
+// must consent to the request, ConsentPage holds the consent screen to render and Code is
+// empty.
+type AuthCodeGrant struct {
+	Code        string
+	ConsentPage []byte
+}
+
+func generateConsentChallenge() (string, error) {
+	return crypto.GenerateRandHexString(32)
+}
+
+// consentRequired determines if any of the scopes require consent that the user has not
+// already granted the client.
+func (s *Service) consentRequired(ctx context.Context, userID int, clientID string, scopes []string) (bool, error) {
+	registered, err := s.registeredScopes(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	grant, err := s.consentStore.GetGrant(ctx, userID, clientID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, sc := range scopes {
+		if registered[sc].RequiresConsent && !containsScope(grant.Scopes, sc) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// requestConsent stores the authorization request and the time the user signed in until the
+// user answers the consent screen, and renders the screen naming the client and listing every
+// requested scope.
+func (s *Service) requestConsent(ctx context.Context, userID int, authTime time.Time, req AuthCodeRequest) ([]byte, error) {
+	registered, err := s.registeredScopes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := s.clientStore.GetByClientID(ctx, req.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clients registered without a name are shown by their client_id.
+	clientName := client.Name
+	if clientName == "" {
+		clientName = client.ClientID
+	}
+
+	var scopes []store.Scope
+	for _, sc := range parseScope(req.Scope) {
+		scopes = append(scopes, registered[sc])
+	}
+
+	cr := store.ConsentRequest{
+		UserID:              userID,
+		ClientID:            req.ClientID,
+		RedirectURL:         req.RedirectURL,
+		State:               req.State,
+		Scope:               req.Scope,
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		AuthTime:            authTime,
+		CreatedAt:           time.Now(),
+	}
+
+	if cr.Challenge, err = generateConsentChallenge(); err != nil {
+		return nil, err
+	}
+
+	if _, err = s.consentStore.InsertRequest(ctx, cr); err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	err = templates.ExecuteTemplate(
+		buf,
+		"consent.html",
+		map[string]interface{}{
+			"clientName": clientName,
+			"scopes":     scopes,
+			"challenge":  cr.Challenge,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Consent records the user's answer to the consent screen identified by the challenge. If
+// the user approves, the requested scopes are remembered for the client and an auth code
+// is issued. Once the client and redirect URL are verified, the original request is always
+// returned so that the caller can redirect back to the client, including when the request
+// is no longer valid or ErrAccessDenied is returned.
+func (s *Service) Consent(ctx context.Context, challenge string, approved bool) (AuthCodeRequest, string, error) {
+	cr, err := s.consentStore.ConsumeRequest(ctx, challenge)
+	if err != nil {
+		return AuthCodeRequest{}, "", err
+	}
+
+	if time.Since(cr.CreatedAt) > consentRequestLifespan {
+		return AuthCodeRequest{}, "", errors.New("consent request has expired")
+	}
+
+	req := AuthCodeRequest{
+		ResponseType:        CodeResponseType,
+		ClientID:            cr.ClientID,
+		RedirectURL:         cr.RedirectURL,
+		State:               cr.State,
+		Scope:               cr.Scope,
+		Nonce:               cr.Nonce,
+		CodeChallenge:       cr.CodeChallenge,
+		CodeChallengeMethod: cr.CodeChallengeMethod,
+	}
+
+	// The client's settings may have changed since the user signed in.
+	granted, err := s.validateAuthCodeRequest(ctx, req)
+	if errors.Is(err, ErrUnknownClient) || errors.Is(err, ErrInvalidRedirectURL) {
+		return AuthCodeRequest{}, "", err
+	} else if err != nil {
+		return req, "", err
+	}
+	req = granted
+
+	if !approved {
+		return req, "", ErrAccessDenied
+	}
+
+	err = s.consentStore.Grant(ctx, store.ConsentGrant{
+		UserID:    cr.UserID,
+		ClientID:  cr.ClientID,
+		Scopes:    parseScope(cr.Scope),
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return AuthCodeRequest{}, "", err
+	}
+
+	code, err := s.issueAuthCode(ctx, cr.UserID, cr.AuthTime, req)
+	if err != nil {
+		return AuthCodeRequest{}, "", err
+	}
+
+	return req, code, nil
+}
+
+// ConsentGrants lists the clients the token's user has granted access to.
+func (s *Service) ConsentGrants(ctx context.Context, token string) ([]store.ConsentGrant, error) {
+	userID, err := s.authenticateUserToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.consentStore.ListGrants(ctx, userID)
+}
+
+// RevokeConsent forgets every scope the token's user has granted the client, and revokes
+// the refresh tokens the client holds for the user. The user will be asked for consent the
+// next time the client requests access.
+func (s *Service) RevokeConsent(ctx context.Context, token, clientID string) error {
+	userID, err := s.authenticateUserToken(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.consentStore.RevokeGrant(ctx, userID, clientID); err != nil {
+		return err
+	}
+
+	return s.refreshTokenStore.RevokeClient(ctx, userID, clientID)
+}
+
+// authenticateUserToken returns the ID of the user the access token was issued to. Only
+// tokens the user obtained by signing in directly are accepted, so that clients cannot
+// manage the user's grants.
+func (s *Service) authenticateUserToken(ctx context.Context, token string) (int, error) {
+	claims, err := s.ValidateToken(ctx, token)
+	if err != nil {
+		return 0, err
+	}
+
+	if claims.ClientID != "" {
+		return 0, ErrInsufficientScope
+	}
+
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return 0, ErrInsufficientScope
+	}
+
+	return id, nil
+}
@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattmeyers/heimdall/store"
+)
+
+type consentStoreStub struct {
+	store.ConsentStore
+	grant   store.ConsentGrant
+	request store.ConsentRequest
+}
+
+func (s consentStoreStub) GetGrant(ctx context.Context, userID int, clientID string) (store.ConsentGrant, error) {
+	return s.grant, nil
+}
+
+func (s consentStoreStub) Grant(ctx context.Context, g store.ConsentGrant) error {
+	return nil
+}
+
+func (s consentStoreStub) InsertRequest(ctx context.Context, cr store.ConsentRequest) (int, error) {
+	return 1, nil
+}
+
+func (s consentStoreStub) ConsumeRequest(ctx context.Context, challenge string) (store.ConsentRequest, error) {
+	if challenge != s.request.Challenge {
+		return store.ConsentRequest{}, errors.New("consent request not found")
+	}
+	return s.request, nil
+}
+
+func TestService_consentRequired(t *testing.T) {
+	scopes := scopeStoreStub{
+		{Name: "openid"},
+		{Name: "email", RequiresConsent: true},
+		{Name: "profile", RequiresConsent: true},
+	}
+
+	tests := []struct {
+		name      string
+		granted   []string
+		requested []string
+		want      bool
+	}{
+		{
+			name:      "No scopes require consent",
+			requested: []string{"openid"},
+			want:      false,
+		},
+		{
+			name:      "Consent not yet granted",
+			requested: []string{"openid", "email"},
+			want:      true,
+		},
+		{
+			name:      "Covered by prior grant",
+			granted:   []string{"openid", "email"},
+			requested: []string{"openid", "email"},
+			want:      false,
+		},
+		{
+			name:      "Request exceeds prior grant",
+			granted:   []string{"email"},
+			requested: []string{"email", "profile"},
+			want:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{
+				scopeStore:   scopes,
+				consentStore: consentStoreStub{grant: store.ConsentGrant{Scopes: tt.granted}},
+			}
+
+			got, err := s.consentRequired(context.Background(), 1, "client", tt.requested)
+			if err != nil {
+				t.Fatalf("consentRequired() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("consentRequired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_Consent(t *testing.T) {
+	client := store.Client{
+		ClientID:      "client",
+		RedirectURLs:  []string{"https://example.com/cb"},
+		AllowedScopes: []string{"openid", "email"},
+		ResponseTypes: []string{CodeResponseType},
+	}
+	scopeRemoved := client
+	scopeRemoved.AllowedScopes = []string{"openid"}
+	redirectRemoved := client
+	redirectRemoved.RedirectURLs = []string{"https://example.com/other"}
+	disabled := client
+	disabled.Disabled = true
+
+	request := store.ConsentRequest{
+		Challenge:   "challenge",
+		UserID:      1,
+		ClientID:    "client",
+		RedirectURL: "https://example.com/cb",
+		State:       "xyz",
+		Scope:       "openid email",
+		AuthTime:    time.Now().Add(-time.Minute).Truncate(time.Second),
+		CreatedAt:   time.Now(),
+	}
+
+	tests := []struct {
+		name         string
+		client       store.Client
+		approved     bool
+		wantErr      error
+		wantCode     ErrorCode
+		wantRedirect bool
+	}{
+		{
+			name:         "Approved",
+			client:       client,
+			approved:     true,
+			wantRedirect: true,
+		},
+		{
+			name:         "Declined",
+			client:       client,
+			approved:     false,
+			wantCode:     AccessDenied,
+			wantRedirect: true,
+		},
+		{
+			name:         "Scope no longer allowed",
+			client:       scopeRemoved,
+			approved:     true,
+			wantCode:     InvalidScope,
+			wantRedirect: true,
+		},
+		{
+			name:     "Redirect URL no longer registered",
+			client:   redirectRemoved,
+			approved: true,
+			wantErr:  ErrInvalidRedirectURL,
+		},
+		{
+			name:     "Client disabled",
+			client:   disabled,
+			approved: true,
+			wantErr:  ErrUnknownClient,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codes := authCodeStoreStub{}
+			s := &Service{
+				clientStore:   clientStoreStub{clients: map[string]store.Client{"client": tt.client}},
+				scopeStore:    scopeStoreStub{{Name: "openid"}, {Name: "email", RequiresConsent: true}},
+				consentStore:  consentStoreStub{request: request},
+				authCodeStore: codes,
+			}
+
+			req, code, err := s.Consent(context.Background(), "challenge", tt.approved)
+
+			var oauthErr *Error
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Consent() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantCode != "":
+				if !errors.As(err, &oauthErr) || oauthErr.Code != tt.wantCode {
+					t.Fatalf("Consent() error = %v, want %s", err, tt.wantCode)
+				}
+			case err != nil:
+				t.Fatalf("Consent() error = %v", err)
+			}
+
+			// Errors may only be sent to verified redirect URLs.
+			if tt.wantRedirect && (req.RedirectURL != request.RedirectURL || req.State != request.State) {
+				t.Errorf("Consent() request = %+v, want the stored redirect URL and state", req)
+			} else if !tt.wantRedirect && req.RedirectURL != "" {
+				t.Errorf("Consent() redirect URL = %q, want none", req.RedirectURL)
+			}
+
+			if tt.wantErr != nil || tt.wantCode != "" {
+				return
+			}
+
+			if got := codes[code].AuthTime; !got.Equal(request.AuthTime) {
+				t.Errorf("auth code auth time = %v, want the sign in time %v", got, request.AuthTime)
+			}
+		})
+	}
+}
+
+func TestService_requestConsent(t *testing.T) {
+	s := &Service{
+		clientStore: clientStoreStub{clients: map[string]store.Client{
+			"named":   {ClientID: "named", Name: "Photo Printer"},
+			"unnamed": {ClientID: "unnamed"},
+		}},
+		scopeStore:   scopeStoreStub{{Name: "email", RequiresConsent: true}},
+		consentStore: consentStoreStub{},
+	}
+
+	tests := []struct {
+		name     string
+		clientID string
+		want     string
+	}{
+		{name: "Named client", clientID: "named", want: "Photo Printer is requesting access"},
+		{name: "Unnamed client", clientID: "unnamed", want: "unnamed is requesting access"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := AuthCodeRequest{ClientID: tt.clientID, Scope: "email"}
+			page, err := s.requestConsent(context.Background(), 1, time.Now(), req)
+			if err != nil {
+				t.Fatalf("requestConsent() error = %v", err)
+			}
+
+			if !strings.Contains(string(page), tt.want) {
+				t.Errorf("requestConsent() page does not contain %q:\n%s", tt.want, page)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package auth
+
+// ErrorCode is an OAuth 2.0 error code (RFC 6749 sections 4.1.2.1 and 5.2).
+type ErrorCode string
+
+const (
+	InvalidRequest          ErrorCode = "invalid_request"
+	InvalidClient           ErrorCode = "invalid_client"
+	InvalidGrant            ErrorCode = "invalid_grant"
+	UnauthorizedClient      ErrorCode = "unauthorized_client"
+	UnsupportedGrantType    ErrorCode = "unsupported_grant_type"
+	UnsupportedResponseType ErrorCode = "unsupported_response_type"
+	InvalidScope            ErrorCode = "invalid_scope"
+	AccessDenied            ErrorCode = "access_denied"
+	ServerError             ErrorCode = "server_error"
+)
+
+// Error is an error that can be reported to the client using an OAuth 2.0 error response.
+// Any other error returned by the service is an internal error.
+type Error struct {
+	Code        ErrorCode
+	Description string
+	// URI optionally identifies a page with more information about the error.
+	URI string
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return string(e.Code)
+	}
+
+	return string(e.Code) + ": " + e.Description
+}
+
+func newError(code ErrorCode, description string) *Error {
+	return &Error{Code: code, Description: description}
+}
+
+// The authorization endpoint must not redirect to an unverified redirect URL, so these
+// errors are reported to the user rather than the client (RFC 6749 section 4.1.2.1).
+var (
+	ErrUnknownClient      = newError(InvalidRequest, "unknown client")
+	ErrInvalidRedirectURL = newError(InvalidRequest, "redirect URL is not registered for the client")
+)
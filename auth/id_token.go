@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// idTokenClaims are the claims encoded into an OpenID Connect ID token (OIDC Core section 2).
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce           string           `json:"nonce,omitempty"`
+	AuthTime        *jwt.NumericDate `json:"auth_time,omitempty"`
+	AccessTokenHash string           `json:"at_hash,omitempty"`
+}
+
+// idTokenParams describe the authentication event an ID token asserts.
+type idTokenParams struct {
+	Subject     string
+	ClientID    string
+	Nonce       string
+	AuthTime    time.Time
+	AccessToken string
+}
+
+// generateIDToken generates an ID token for the client. It is signed by the same key as
+// access tokens.
+func generateIDToken(settings JWTSettings, p idTokenParams) (string, error) {
+	if err := settings.validate(); err != nil {
+		return "", err
+	} else if !settings.signsIDTokens() {
+		return "", errors.New("ID tokens require an asymmetric signing key")
+	}
+
+	now := time.Now()
+	claims := &idTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    settings.Issuer,
+			Subject:   p.Subject,
+			Audience:  jwt.ClaimStrings{p.ClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Second * time.Duration(settings.idTokenLifespan()))),
+		},
+		Nonce:           p.Nonce,
+		AuthTime:        jwt.NewNumericDate(p.AuthTime),
+		AccessTokenHash: accessTokenHash(p.AccessToken, settings.activeKey().Algorithm),
+	}
+
+	return signJWT(settings, "JWT", claims)
+}
+
+// idTokenLifespan is the number of seconds an ID token is valid.
+func (s JWTSettings) idTokenLifespan() int {
+	if s.IDTokenLifespan > 0 {
+		return s.IDTokenLifespan
+	}
+
+	return s.Lifespan
+}
+
+// accessTokenHash computes the at_hash claim: the base64url encoded left half of the hash of
+// the access token, using the hash function of the ID token's signing algorithm.
+func accessTokenHash(accessToken string, alg signingAlgorithm) string {
+	var h hash.Hash
+	switch alg {
+	case EdDSAAlgorithm:
+		h = sha512.New()
+	default:
+		h = sha256.New()
+	}
+
+	h.Write([]byte(accessToken))
+	sum := h.Sum(nil)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
@@ -0,0 +1,44 @@
+package auth
+
+import "testing"
+
+func Test_accessTokenHash(t *testing.T) {
+	// Test vector taken from appendix A.3 of OpenID Connect Core 1.0.
+	got := accessTokenHash("jHkWEdUXMU1BwAsC4vtUsZwnNvTIxEl0z9K3vx5KF0Y", RSA256Algorithm)
+	if want := "77QmUPtjPfzWtF2AnpK9RQ"; got != want {
+		t.Errorf("accessTokenHash() = %v, want %v", got, want)
+	}
+}
+
+func Test_generateIDToken(t *testing.T) {
+	key, err := GenerateKey(ECDSA256Algorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		settings JWTSettings
+		wantErr  bool
+	}{
+		{
+			name:     "Asymmetric key",
+			settings: JWTSettings{Issuer: "Heimdall", Lifespan: 60, Algorithm: ECDSA256Algorithm, Keys: NewKeySet(key)},
+			wantErr:  false,
+		},
+		{
+			// Relying parties cannot verify tokens signed with the server's own secret.
+			name:     "HS256",
+			settings: testJWTSettings,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := generateIDToken(tt.settings, idTokenParams{Subject: "1", ClientID: "client"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("generateIDToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
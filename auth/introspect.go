@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/mattmeyers/heimdall/store"
+)
+
+// Introspection describes the state of a token as defined by RFC 7662 section 2.2. Only
+// Active is set for tokens that are unknown, expired, or revoked, and for tokens issued to
+// a client that has been disabled or deleted.
+type Introspection struct {
+	Active    bool
+	Subject   string
+	ClientID  string
+	Scope     string
+	Issuer    string
+	TokenType string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// Introspect reports the state of an access or refresh token to an authenticated client.
+// Tokens are only reported as active to the client they were issued to, except that
+// resource servers may also introspect access tokens issued to other clients. Public
+// clients cannot introspect tokens since they cannot authenticate. The hint only determines
+// which token type is tried first.
+func (s *Service) Introspect(ctx context.Context, token, tokenTypeHint string, ca ClientAuth) (Introspection, error) {
+	c, err := s.authenticateClient(ctx, ca)
+	if err != nil {
+		return Introspection{}, err
+	} else if c.Type == store.PublicClient {
+		return Introspection{}, ErrInvalidClient
+	}
+
+	introspectors := []func(context.Context, string, store.Client) (Introspection, error){
+		s.introspectAccessToken,
+		s.introspectRefreshToken,
+	}
+	if tokenTypeHint == RefreshTokenHint {
+		introspectors[0], introspectors[1] = introspectors[1], introspectors[0]
+	}
+
+	for _, introspect := range introspectors {
+		i, err := introspect(ctx, token, c)
+		if err != nil || i.Active {
+			return i, err
+		}
+	}
+
+	return Introspection{}, nil
+}
+
+func (s *Service) introspectAccessToken(ctx context.Context, token string, c store.Client) (Introspection, error) {
+	claims, err := validateJWT(token, s.jwtSettings)
+	if err != nil {
+		return Introspection{}, nil
+	}
+
+	if !c.ResourceServer && claims.ClientID != c.ClientID {
+		return Introspection{}, nil
+	}
+
+	revoked, err := s.revokedTokenStore.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return Introspection{}, err
+	} else if revoked || !s.tokenOwnerActive(ctx, claims) {
+		return Introspection{}, nil
+	}
+
+	tc := claims.toTokenClaims()
+
+	return Introspection{
+		Active:    true,
+		Subject:   tc.Subject,
+		ClientID:  tc.ClientID,
+		Scope:     formatScope(tc.Scopes),
+		Issuer:    tc.Issuer,
+		TokenType: "Bearer",
+		IssuedAt:  tc.IssuedAt,
+		ExpiresAt: tc.ExpiresAt,
+	}, nil
+}
+
+func (s *Service) introspectRefreshToken(ctx context.Context, token string, c store.Client) (Introspection, error) {
+	rt, err := s.refreshTokenStore.GetByToken(ctx, token)
+	if err != nil || rt.ClientID != c.ClientID {
+		return Introspection{}, nil
+	}
+
+	if rt.Used || rt.Revoked || time.Now().After(rt.ExpiresAt) {
+		return Introspection{}, nil
+	}
+
+	return Introspection{
+		Active:    true,
+		Subject:   strconv.Itoa(rt.UserID),
+		ClientID:  rt.ClientID,
+		Scope:     rt.Scope,
+		Issuer:    s.jwtSettings.Issuer,
+		IssuedAt:  rt.CreatedAt,
+		ExpiresAt: rt.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mattmeyers/heimdall/store"
+)
+
+func TestService_Introspect(t *testing.T) {
+	secretHash := hashSecret(t, "secret")
+	clients := clientStoreStub{clients: map[string]store.Client{
+		"client": {ClientID: "client", SecretHash: secretHash, TokenEndpointAuthMethod: store.ClientSecretPost},
+		"other":  {ClientID: "other", SecretHash: secretHash, TokenEndpointAuthMethod: store.ClientSecretPost},
+		"api": {
+			ClientID:                "api",
+			SecretHash:              secretHash,
+			TokenEndpointAuthMethod: store.ClientSecretPost,
+			ResourceServer:          true,
+		},
+		"public": {ClientID: "public", Type: store.PublicClient, TokenEndpointAuthMethod: store.ClientAuthNone},
+	}}
+
+	accessToken, err := generateJWT(testJWTSettings, accessTokenParams{Subject: "1", ClientID: "other", Scopes: []string{"read"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ownToken, err := generateJWT(testJWTSettings, accessTokenParams{Subject: "1", ClientID: "client", Scopes: []string{"read"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokedToken, err := generateJWT(testJWTSettings, accessTokenParams{Subject: "1", ClientID: "client"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expiresAt := time.Now().Add(time.Hour)
+	refreshTokens := &refreshTokenStoreStub{tokens: map[string]store.RefreshToken{
+		"refresh":       {Token: "refresh", UserID: 1, ClientID: "client", Scope: "read", ExpiresAt: expiresAt},
+		"other-refresh": {Token: "other-refresh", UserID: 1, ClientID: "other", Scope: "read", ExpiresAt: expiresAt},
+	}}
+
+	s := &Service{
+		userStore:         userStoreStub{1: {ID: 1}},
+		clientStore:       clients,
+		refreshTokenStore: refreshTokens,
+		revokedTokenStore: revokedTokenStoreStub{revokedToken.ID: {TokenID: revokedToken.ID}},
+		jwtSettings:       testJWTSettings,
+	}
+
+	confidential := ClientAuth{ClientID: "client", ClientSecret: "secret", Method: store.ClientSecretPost}
+	resourceServer := ClientAuth{ClientID: "api", ClientSecret: "secret", Method: store.ClientSecretPost}
+
+	tests := []struct {
+		name       string
+		token      string
+		ca         ClientAuth
+		wantErr    error
+		wantActive bool
+	}{
+		{
+			name:       "Own access token",
+			token:      ownToken.AccessToken,
+			ca:         confidential,
+			wantActive: true,
+		},
+		{
+			name:       "Another client's access token",
+			token:      accessToken.AccessToken,
+			ca:         confidential,
+			wantActive: false,
+		},
+		{
+			name:       "Another client's access token at a resource server",
+			token:      accessToken.AccessToken,
+			ca:         resourceServer,
+			wantActive: true,
+		},
+		{
+			name:       "Another client's refresh token at a resource server",
+			token:      "refresh",
+			ca:         resourceServer,
+			wantActive: false,
+		},
+		{
+			name:       "Own refresh token",
+			token:      "refresh",
+			ca:         confidential,
+			wantActive: true,
+		},
+		{
+			name:       "Unknown token",
+			token:      "unknown",
+			ca:         confidential,
+			wantActive: false,
+		},
+		{
+			name:       "Revoked access token",
+			token:      revokedToken.AccessToken,
+			ca:         resourceServer,
+			wantActive: false,
+		},
+		{
+			name:       "Another client's refresh token",
+			token:      "other-refresh",
+			ca:         confidential,
+			wantActive: false,
+		},
+		{
+			name:    "Public client",
+			token:   accessToken.AccessToken,
+			ca:      ClientAuth{ClientID: "public", Method: store.ClientAuthNone},
+			wantErr: ErrInvalidClient,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i, err := s.Introspect(context.Background(), tt.token, "", tt.ca)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Introspect() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if i.Active != tt.wantActive {
+				t.Errorf("Introspect() active = %v, want %v", i.Active, tt.wantActive)
+			}
+
+			if !i.Active && i != (Introspection{}) {
+				t.Errorf("Introspect() = %+v, want only active:false for an inactive token", i)
+			}
+		})
+	}
+}
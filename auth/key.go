@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Key is a private key used to sign JWTs, along with the information needed to select it
+// when verifying a token.
+type Key struct {
+	// ID is the value of the kid header in tokens signed by this key.
+	ID        string
+	Algorithm signingAlgorithm
+	private   interface{}
+	// expiresAt is the time after which the key can no longer verify tokens. The zero
+	// time indicates that the key does not expire.
+	expiresAt time.Time
+}
+
+// NewKey wraps a private key for use in signing JWTs. The signing algorithm is determined
+// by the key type: RSA keys use RS256, P-256 ECDSA keys use ES256, and Ed25519 keys use EdDSA.
+// The key ID is derived from the public key.
+func NewKey(private crypto.PrivateKey) (Key, error) {
+	var alg signingAlgorithm
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		alg = RSA256Algorithm
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return Key{}, errors.New("ECDSA keys must use the P-256 curve")
+		}
+		alg = ECDSA256Algorithm
+	case ed25519.PrivateKey:
+		alg = EdDSAAlgorithm
+	default:
+		return Key{}, fmt.Errorf("unsupported key type %T", private)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(private.(crypto.Signer).Public())
+	if err != nil {
+		return Key{}, err
+	}
+
+	return Key{ID: keyID(der), Algorithm: alg, private: private}, nil
+}
+
+// GenerateKey generates a new private key for the provided asymmetric algorithm.
+func GenerateKey(alg signingAlgorithm) (Key, error) {
+	var private crypto.PrivateKey
+	var err error
+	switch alg {
+	case RSA256Algorithm:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case ECDSA256Algorithm:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case EdDSAAlgorithm:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return Key{}, fmt.Errorf("cannot generate keys for algorithm %s", alg)
+	}
+	if err != nil {
+		return Key{}, err
+	}
+
+	return NewKey(private)
+}
+
+// newHMACKey wraps a shared secret for use with HS256.
+func newHMACKey(secret string) Key {
+	return Key{ID: keyID([]byte(secret)), Algorithm: HMAC256Algorithm, private: []byte(secret)}
+}
+
+func keyID(b []byte) string {
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
+
+// LoadKey reads a PEM encoded private key from the provided file. PKCS #8, PKCS #1 (RSA), and
+// SEC 1 (ECDSA) encodings are supported.
+func LoadKey(path string) (Key, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, err
+	}
+
+	return ParseKey(b)
+}
+
+// ParseKey parses a PEM encoded private key. See LoadKey for the supported encodings.
+func ParseKey(b []byte) (Key, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return Key{}, errors.New("key must be PEM encoded")
+	}
+
+	var private crypto.PrivateKey
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return Key{}, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return Key{}, err
+	}
+
+	return NewKey(private)
+}
+
+// MarshalPEM encodes the private key as a PKCS #8 PEM block.
+func (k Key) MarshalPEM() ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.private)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func (k Key) isExpired(now time.Time) bool {
+	return !k.expiresAt.IsZero() && now.After(k.expiresAt)
+}
+
+// signingKey returns the key in the form expected by the jwt package when signing.
+func (k Key) signingKey() interface{} {
+	return k.private
+}
+
+// verificationKey returns the key in the form expected by the jwt package when verifying.
+func (k Key) verificationKey() interface{} {
+	if s, ok := k.private.(crypto.Signer); ok {
+		return s.Public()
+	}
+
+	return k.private
+}
+
+// KeySet is a collection of keys indexed by key ID. The active key signs new tokens, while
+// every unexpired key in the set can be used to verify tokens. A key set is safe for
+// concurrent use, and its keys can be replaced while in use.
+type KeySet struct {
+	mu     sync.RWMutex
+	active string
+	keys   map[string]Key
+}
+
+// NewKeySet constructs a key set that signs with the active key. The additional keys are
+// only used for verification.
+func NewKeySet(active Key, keys ...Key) *KeySet {
+	s := &KeySet{}
+	s.replace(active, keys...)
+
+	return s
+}
+
+func (s *KeySet) replace(active Key, keys ...Key) {
+	m := map[string]Key{active.ID: active}
+	for _, k := range keys {
+		m[k.ID] = k
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active = active.ID
+	s.keys = m
+}
+
+// Active returns the key used to sign new tokens.
+func (s *KeySet) Active() Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.keys[s.active]
+}
+
+// All returns every unexpired key in the set, starting with the active key.
+func (s *KeySet) All() []Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	keys := []Key{s.keys[s.active]}
+	for id, k := range s.keys {
+		if id != s.active && !k.isExpired(now) {
+			keys = append(keys, k)
+		}
+	}
+
+	return keys
+}
+
+// Get returns the key with the provided ID. Expired keys are never returned.
+func (s *KeySet) Get(id string) (Key, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	k, ok := s.keys[id]
+	if !ok || k.isExpired(time.Now()) {
+		return Key{}, false
+	}
+
+	return k, true
+}
@@ -0,0 +1,272 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mattmeyers/heimdall/store"
+)
+
+// KeyRotationSettings are the available configuration values for rotating signing keys.
+type KeyRotationSettings struct {
+	// Algorithm is the signing algorithm used for newly generated keys.
+	Algorithm signingAlgorithm
+	// Interval is how long a key remains active before it is rotated.
+	Interval time.Duration
+	// RetiredLifespan is how long a retired key can still verify tokens. This must be at
+	// least as long as the lifespan of any token signed by the key.
+	RetiredLifespan time.Duration
+}
+
+func (s KeyRotationSettings) validate() error {
+	if !s.Algorithm.isValid() || s.Algorithm == HMAC256Algorithm {
+		return errors.New("key rotation requires an asymmetric signing algorithm")
+	}
+
+	if s.Interval <= 0 {
+		return errors.New("key rotation interval must be positive")
+	}
+
+	if s.RetiredLifespan <= 0 {
+		return errors.New("retired key lifespan must be positive")
+	}
+
+	return nil
+}
+
+// KeyManager keeps a KeySet in sync with the keys persisted in a SigningKeyStore, and
+// rotates them. A rotation promotes the next key to active, retires the active key, and
+// generates a new next key. Retired keys remain in the key set until they expire so that
+// tokens signed before the rotation can still be verified.
+type KeyManager struct {
+	store    store.SigningKeyStore
+	settings KeyRotationSettings
+	keys     *KeySet
+
+	activatedAt time.Time
+}
+
+// NewKeyManager constructs a key manager and loads the current keys from the store. If the
+// store contains no usable active key, the keys are rotated.
+func NewKeyManager(ctx context.Context, s store.SigningKeyStore, settings KeyRotationSettings) (*KeyManager, error) {
+	if err := settings.validate(); err != nil {
+		return nil, err
+	}
+
+	m := &KeyManager{store: s, settings: settings, keys: &KeySet{}}
+	if err := m.Load(ctx); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// KeySet returns the key set managed by m. The same key set is updated in place on every
+// load, so it can be shared with JWTSettings.
+func (m *KeyManager) KeySet() *KeySet {
+	return m.keys
+}
+
+// Load reads the keys from the store into the key set. If the store has no active key
+// using the configured algorithm, the keys are rotated first.
+func (m *KeyManager) Load(ctx context.Context) error {
+	keys, err := m.store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	active, ok := findActiveKey(keys)
+	if !ok {
+		return m.Rotate(ctx)
+	}
+
+	k, err := ParseKey(active.PrivateKey)
+	if err != nil {
+		return err
+	} else if k.Algorithm != m.settings.Algorithm {
+		return m.Rotate(ctx)
+	}
+
+	return m.load(keys)
+}
+
+// Rotate promotes the next key to active, retires the current active key, and generates a
+// new next key. Retired keys that have expired are deleted.
+func (m *KeyManager) Rotate(ctx context.Context) error {
+	keys, err := m.store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	var next *store.SigningKey
+	var previous []store.SigningKey
+	for i, k := range keys {
+		switch k.State {
+		case store.SigningKeyNext:
+			if next == nil && m.hasConfiguredAlgorithm(k) {
+				next = &keys[i]
+			} else if err := m.store.Delete(ctx, k.ID); err != nil {
+				return err
+			}
+		case store.SigningKeyActive:
+			previous = append(previous, k)
+		case store.SigningKeyRetired:
+			if now.After(k.RetiredAt.Add(m.settings.RetiredLifespan)) {
+				if err := m.store.Delete(ctx, k.ID); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// The new active key is stored before the previous one is retired so that there is
+	// always an active key for other instances sharing the store to load.
+	if next == nil {
+		k, err := m.generate(ctx, store.SigningKeyActive, now)
+		if err != nil {
+			return err
+		}
+		next = &k
+	} else {
+		next.State = store.SigningKeyActive
+		next.ActivatedAt = now
+		if err := m.store.Update(ctx, *next); err != nil {
+			return err
+		}
+	}
+
+	for _, k := range previous {
+		k.State = store.SigningKeyRetired
+		k.RetiredAt = now
+		if err := m.store.Update(ctx, k); err != nil {
+			return err
+		}
+	}
+
+	if _, err := m.generate(ctx, store.SigningKeyNext, now); err != nil {
+		return err
+	}
+
+	keys, err = m.store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	return m.load(keys)
+}
+
+// Run reloads the keys from the store on every tick of the provided interval, rotating
+// them once the active key has been active for the configured rotation interval. Errors
+// are passed to onError and do not stop the loop. Run blocks until ctx is cancelled.
+func (m *KeyManager) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := m.Load(ctx); err != nil {
+			onError(err)
+			continue
+		}
+
+		if time.Since(m.activatedAt) >= m.settings.Interval {
+			if err := m.Rotate(ctx); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func (m *KeyManager) generate(ctx context.Context, state store.SigningKeyState, now time.Time) (store.SigningKey, error) {
+	k, err := GenerateKey(m.settings.Algorithm)
+	if err != nil {
+		return store.SigningKey{}, err
+	}
+
+	b, err := k.MarshalPEM()
+	if err != nil {
+		return store.SigningKey{}, err
+	}
+
+	sk := store.SigningKey{ID: k.ID, State: state, PrivateKey: b, CreatedAt: now}
+	if state == store.SigningKeyActive {
+		sk.ActivatedAt = now
+	}
+
+	if err := m.store.Insert(ctx, sk); err != nil {
+		return store.SigningKey{}, err
+	}
+
+	return sk, nil
+}
+
+func (m *KeyManager) hasConfiguredAlgorithm(sk store.SigningKey) bool {
+	k, err := ParseKey(sk.PrivateKey)
+	return err == nil && k.Algorithm == m.settings.Algorithm
+}
+
+// load replaces the contents of the key set with the provided keys. Retired keys are
+// given an expiry, and expired keys are skipped.
+func (m *KeyManager) load(keys []store.SigningKey) error {
+	active, ok := findActiveKey(keys)
+	if !ok {
+		return errors.New("no active signing key")
+	}
+
+	now := time.Now()
+
+	var activeKey Key
+	var others []Key
+	for _, sk := range keys {
+		var expiresAt time.Time
+		if sk.State == store.SigningKeyRetired {
+			expiresAt = sk.RetiredAt.Add(m.settings.RetiredLifespan)
+			if now.After(expiresAt) {
+				continue
+			}
+		} else if sk.State == store.SigningKeyActive && sk.ID != active.ID {
+			// A concurrent rotation may briefly leave two active keys. The older one is
+			// about to be retired, so it is kept for verification only.
+			expiresAt = now.Add(m.settings.RetiredLifespan)
+		}
+
+		k, err := ParseKey(sk.PrivateKey)
+		if err != nil {
+			return err
+		}
+		k.expiresAt = expiresAt
+
+		if sk.ID == active.ID {
+			activeKey = k
+		} else {
+			others = append(others, k)
+		}
+	}
+
+	m.keys.replace(activeKey, others...)
+	m.activatedAt = active.ActivatedAt
+
+	return nil
+}
+
+// findActiveKey returns the most recently activated active key.
+func findActiveKey(keys []store.SigningKey) (store.SigningKey, bool) {
+	var active store.SigningKey
+	var ok bool
+	for _, k := range keys {
+		if k.State == store.SigningKeyActive && (!ok || k.ActivatedAt.After(active.ActivatedAt)) {
+			active = k
+			ok = true
+		}
+	}
+
+	return active, ok
+}
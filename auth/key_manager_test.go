@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/mattmeyers/heimdall/store"
+	"github.com/mattmeyers/heimdall/store/file"
+)
+
+func TestKeyManager_Rotate(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := file.NewSigningKeyStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := NewKeyManager(ctx, s, KeyRotationSettings{
+		Algorithm:       ECDSA256Algorithm,
+		Interval:        time.Hour,
+		RetiredLifespan: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewKeyManager() error = %v", err)
+	}
+
+	keys, err := s.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := countStates(keys); got[store.SigningKeyActive] != 1 || got[store.SigningKeyNext] != 1 {
+		t.Fatalf("NewKeyManager() stored keys %v, want one active and one next", got)
+	}
+
+	settings := JWTSettings{
+		Issuer:    "Heimdall",
+		Lifespan:  60,
+		Algorithm: ECDSA256Algorithm,
+		Keys:      m.KeySet(),
+	}
+
+	before, err := generateJWT(settings, accessTokenParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Rotate(ctx); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+
+	keys, err = s.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := countStates(keys)
+	if got[store.SigningKeyActive] != 1 || got[store.SigningKeyNext] != 1 || got[store.SigningKeyRetired] != 1 {
+		t.Fatalf("Rotate() stored keys %v, want one of each state", got)
+	}
+
+	after, err := generateJWT(settings, accessTokenParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if kid(t, before.AccessToken) == kid(t, after.AccessToken) {
+		t.Error("Rotate() did not change the active key")
+	}
+
+	if _, err := validateJWT(before.AccessToken, settings); err != nil {
+		t.Errorf("validateJWT() rejected token signed by retired key: %v", err)
+	}
+
+	// Once the retired key expires, tokens it signed are no longer accepted.
+	m.settings.RetiredLifespan = time.Nanosecond
+	if err := m.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := validateJWT(before.AccessToken, settings); err == nil {
+		t.Error("validateJWT() accepted token signed by expired key")
+	}
+
+	if _, err := validateJWT(after.AccessToken, settings); err != nil {
+		t.Errorf("validateJWT() error = %v", err)
+	}
+}
+
+func countStates(keys []store.SigningKey) map[store.SigningKeyState]int {
+	counts := make(map[store.SigningKeyState]int)
+	for _, k := range keys {
+		counts[k.State]++
+	}
+
+	return counts
+}
+
+func kid(t *testing.T, token string) string {
+	t.Helper()
+
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return parsed.Header["kid"].(string)
+}
@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func generateTestKeys(t *testing.T) map[string]crypto.PrivateKey {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]crypto.PrivateKey{"RS256": rsaKey, "ES256": ecKey, "EdDSA": edKey}
+}
+
+func TestParseKey(t *testing.T) {
+	for alg, private := range generateTestKeys(t) {
+		t.Run(alg, func(t *testing.T) {
+			der, err := x509.MarshalPKCS8PrivateKey(private)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			key, err := ParseKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+			if err != nil {
+				t.Fatalf("ParseKey() error = %v", err)
+			}
+
+			if string(key.Algorithm) != alg {
+				t.Errorf("ParseKey() algorithm = %v, want %v", key.Algorithm, alg)
+			}
+
+			if key.ID == "" {
+				t.Error("ParseKey() returned an empty key ID")
+			}
+		})
+	}
+
+	t.Run("Unsupported curve", func(t *testing.T) {
+		private, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		der, err := x509.MarshalECPrivateKey(private)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ParseKey(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})); err == nil {
+			t.Error("ParseKey() expected error for P-384 key")
+		}
+	})
+
+	t.Run("Not PEM encoded", func(t *testing.T) {
+		if _, err := ParseKey([]byte("not a key")); err == nil {
+			t.Error("ParseKey() expected error for malformed input")
+		}
+	})
+}
+
+func Test_generateJWT_keySet(t *testing.T) {
+	keys := generateTestKeys(t)
+	other, err := NewKey(keys["EdDSA"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for alg, private := range keys {
+		t.Run(alg, func(t *testing.T) {
+			key, err := NewKey(private)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			settings := JWTSettings{
+				Issuer:    "Heimdall",
+				Lifespan:  60,
+				Algorithm: key.Algorithm,
+				Keys:      NewKeySet(key),
+			}
+
+			token, err := generateJWT(settings, accessTokenParams{})
+			if err != nil {
+				t.Fatalf("generateJWT() error = %v", err)
+			}
+
+			parsed, _, err := new(jwt.Parser).ParseUnverified(token.AccessToken, jwt.MapClaims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if parsed.Header["kid"] != key.ID {
+				t.Errorf("generateJWT() kid = %v, want %v", parsed.Header["kid"], key.ID)
+			}
+
+			if _, err := validateJWT(token.AccessToken, settings); err != nil {
+				t.Errorf("validateJWT() error = %v", err)
+			}
+
+			if alg == "EdDSA" {
+				return
+			}
+
+			// A token must not validate against a key set that does not contain its key.
+			settings.Keys = NewKeySet(other)
+			settings.Algorithm = other.Algorithm
+			if _, err := validateJWT(token.AccessToken, settings); err == nil {
+				t.Error("validateJWT() expected error for unknown key")
+			}
+		})
+	}
+}
+
+func TestKey_publicJWK(t *testing.T) {
+	for alg, private := range generateTestKeys(t) {
+		t.Run(alg, func(t *testing.T) {
+			key, err := NewKey(private)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			jwk, ok := key.publicJWK()
+			if !ok {
+				t.Fatal("publicJWK() returned no key")
+			}
+
+			if jwk.KeyID != key.ID || jwk.Algorithm != alg || jwk.Use != "sig" {
+				t.Errorf("publicJWK() = %+v", jwk)
+			}
+
+			switch alg {
+			case "RS256":
+				if jwk.KeyType != "RSA" || jwk.E != "AQAB" || jwk.N == "" {
+					t.Errorf("publicJWK() = %+v", jwk)
+				}
+			case "ES256":
+				// P-256 coordinates are 32 bytes, or 43 base64url characters.
+				if jwk.KeyType != "EC" || jwk.Curve != "P-256" || len(jwk.X) != 43 || len(jwk.Y) != 43 {
+					t.Errorf("publicJWK() = %+v", jwk)
+				}
+			case "EdDSA":
+				if jwk.KeyType != "OKP" || jwk.Curve != "Ed25519" || len(jwk.X) != 43 {
+					t.Errorf("publicJWK() = %+v", jwk)
+				}
+			}
+		})
+	}
+
+	if _, ok := newHMACKey("secret").publicJWK(); ok {
+		t.Error("publicJWK() returned a key for a shared secret")
+	}
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/mattmeyers/heimdall/store"
+)
+
+// The OAuth 2.0 grant types accepted by the token endpoint.
+const (
+	AuthorizationCodeGrant = "authorization_code"
+	RefreshTokenGrant      = "refresh_token"
+	ClientCredentialsGrant = "client_credentials"
+)
+
+// CodeResponseType is the only response type accepted by the authorization endpoint.
+const CodeResponseType = "code"
+
+var (
+	supportedGrantTypes        = []string{AuthorizationCodeGrant, RefreshTokenGrant, ClientCredentialsGrant}
+	supportedResponseTypes     = []string{CodeResponseType}
+	supportedClientAuthMethods = []string{string(store.ClientSecretBasic), string(store.ClientSecretPost), string(store.ClientAuthNone)}
+	supportedClaims            = []string{"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "at_hash", "email", "email_verified", "name"}
+
+	// confidentialClientAuthMethods are used at endpoints that public clients cannot access.
+	confidentialClientAuthMethods = []string{string(store.ClientSecretBasic), string(store.ClientSecretPost)}
+)
+
+// Endpoints are the absolute URLs at which the server's endpoints are exposed.
+type Endpoints struct {
+	Authorization string
+	Token         string
+	Revocation    string
+	Introspection string
+	JWKS          string
+	UserInfo      string
+}
+
+// ServerMetadata describes the authorization server as defined by RFC 8414 and OpenID
+// Connect Discovery 1.0.
+type ServerMetadata struct {
+	Issuer                           string   `json:"issuer"`
+	AuthorizationEndpoint            string   `json:"authorization_endpoint"`
+	TokenEndpoint                    string   `json:"token_endpoint"`
+	RevocationEndpoint               string   `json:"revocation_endpoint"`
+	IntrospectionEndpoint            string   `json:"introspection_endpoint"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	UserInfoEndpoint                 string   `json:"userinfo_endpoint"`
+	ScopesSupported                  []string `json:"scopes_supported"`
+	ResponseTypesSupported           []string `json:"response_types_supported"`
+	GrantTypesSupported              []string `json:"grant_types_supported"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported,omitempty"`
+	TokenEndpointAuthMethods         []string `json:"token_endpoint_auth_methods_supported"`
+	RevocationEndpointAuthMethods    []string `json:"revocation_endpoint_auth_methods_supported"`
+	IntrospectionEndpointAuthMethods []string `json:"introspection_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported    []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                  []string `json:"claims_supported"`
+}
+
+// Issuer returns the issuer identifier placed in every token the service signs.
+func (s *Service) Issuer() string {
+	return s.jwtSettings.Issuer
+}
+
+// Metadata describes the capabilities of the service. The endpoints are provided by the
+// caller since they depend on where the service is mounted. The openid scope and ID token
+// signing algorithms are left out if the server cannot sign ID tokens.
+func (s *Service) Metadata(ctx context.Context, endpoints Endpoints) (ServerMetadata, error) {
+	scopes, err := s.scopeStore.List(ctx)
+	if err != nil {
+		return ServerMetadata{}, err
+	}
+
+	scopeNames := make([]string, 0, len(scopes))
+	for _, sc := range scopes {
+		if sc.Name == OpenIDScope && !s.jwtSettings.signsIDTokens() {
+			continue
+		}
+		scopeNames = append(scopeNames, sc.Name)
+	}
+
+	var idTokenAlgs []string
+	if s.jwtSettings.signsIDTokens() {
+		idTokenAlgs = []string{string(s.jwtSettings.Algorithm)}
+	}
+
+	return ServerMetadata{
+		Issuer:                           s.jwtSettings.Issuer,
+		AuthorizationEndpoint:            endpoints.Authorization,
+		TokenEndpoint:                    endpoints.Token,
+		RevocationEndpoint:               endpoints.Revocation,
+		IntrospectionEndpoint:            endpoints.Introspection,
+		JWKSURI:                          endpoints.JWKS,
+		UserInfoEndpoint:                 endpoints.UserInfo,
+		ScopesSupported:                  scopeNames,
+		ResponseTypesSupported:           supportedResponseTypes,
+		GrantTypesSupported:              supportedGrantTypes,
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: idTokenAlgs,
+		TokenEndpointAuthMethods:         supportedClientAuthMethods,
+		RevocationEndpointAuthMethods:    supportedClientAuthMethods,
+		IntrospectionEndpointAuthMethods: confidentialClientAuthMethods,
+		CodeChallengeMethodsSupported:    []string{string(S256ChallengeMethod), string(PlainChallengeMethod)},
+		ClaimsSupported:                  supportedClaims,
+	}, nil
+}
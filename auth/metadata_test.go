@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mattmeyers/heimdall/store"
+)
+
+type scopeStoreStub []store.Scope
+
+func (s scopeStoreStub) List(ctx context.Context) ([]store.Scope, error) { return s, nil }
+
+func (s scopeStoreStub) Create(ctx context.Context, sc store.Scope) (int, error) {
+	return len(s) + 1, nil
+}
+
+func TestService_Metadata(t *testing.T) {
+	s := &Service{
+		scopeStore:  scopeStoreStub{{Name: "openid"}, {Name: "read"}},
+		jwtSettings: JWTSettings{Issuer: "https://auth.example.com", Algorithm: ECDSA256Algorithm},
+	}
+
+	got, err := s.Metadata(context.Background(), Endpoints{
+		Authorization: "https://auth.example.com/auth",
+		Token:         "https://auth.example.com/oauth/token",
+	})
+	if err != nil {
+		t.Fatalf("Metadata() error = %v", err)
+	}
+
+	if got.Issuer != "https://auth.example.com" {
+		t.Errorf("Issuer = %q", got.Issuer)
+	}
+	if got.AuthorizationEndpoint != "https://auth.example.com/auth" || got.TokenEndpoint != "https://auth.example.com/oauth/token" {
+		t.Errorf("unexpected endpoints: %q, %q", got.AuthorizationEndpoint, got.TokenEndpoint)
+	}
+	if !reflect.DeepEqual(got.ScopesSupported, []string{"openid", "read"}) {
+		t.Errorf("ScopesSupported = %v", got.ScopesSupported)
+	}
+	if !reflect.DeepEqual(got.IDTokenSigningAlgValuesSupported, []string{"ES256"}) {
+		t.Errorf("IDTokenSigningAlgValuesSupported = %v", got.IDTokenSigningAlgValuesSupported)
+	}
+	if !reflect.DeepEqual(got.GrantTypesSupported, []string{"authorization_code", "refresh_token", "client_credentials"}) {
+		t.Errorf("GrantTypesSupported = %v", got.GrantTypesSupported)
+	}
+	if !reflect.DeepEqual(got.CodeChallengeMethodsSupported, []string{"S256", "plain"}) {
+		t.Errorf("CodeChallengeMethodsSupported = %v", got.CodeChallengeMethodsSupported)
+	}
+}
+
+func TestService_Metadata_hmac(t *testing.T) {
+	s := &Service{
+		scopeStore:  scopeStoreStub{{Name: "openid"}, {Name: "read"}},
+		jwtSettings: JWTSettings{Issuer: "https://auth.example.com", Algorithm: HMAC256Algorithm},
+	}
+
+	got, err := s.Metadata(context.Background(), Endpoints{})
+	if err != nil {
+		t.Fatalf("Metadata() error = %v", err)
+	}
+
+	// ID tokens are not issued with HS256, so OpenID Connect is not advertised.
+	if !reflect.DeepEqual(got.ScopesSupported, []string{"read"}) {
+		t.Errorf("ScopesSupported = %v", got.ScopesSupported)
+	}
+	if got.IDTokenSigningAlgValuesSupported != nil {
+		t.Errorf("IDTokenSigningAlgValuesSupported = %v", got.IDTokenSigningAlgValuesSupported)
+	}
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+)
+
+type codeChallengeMethod string
+
+// The supported PKCE code challenge methods (RFC 7636 section 4.2).
+const (
+	PlainChallengeMethod codeChallengeMethod = "plain"
+	S256ChallengeMethod  codeChallengeMethod = "S256"
+)
+
+func (m codeChallengeMethod) isValid() bool {
+	return m == PlainChallengeMethod || m == S256ChallengeMethod
+}
+
+// Both the code verifier and plain code challenges must be between 43 and 128 characters.
+const (
+	minCodeVerifierLength = 43
+	maxCodeVerifierLength = 128
+)
+
+// isValidCodeVerifier determines if the provided string is a syntactically valid code
+// verifier as defined by section 4.1 of RFC 7636. The same rules apply to challenges.
+func isValidCodeVerifier(v string) bool {
+	if len(v) < minCodeVerifierLength || len(v) > maxCodeVerifierLength {
+		return false
+	}
+
+	for _, c := range v {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9':
+		case c == '-', c == '.', c == '_', c == '~':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// normalizeCodeChallenge validates the challenge parameters sent to the authorization
+// endpoint and returns the challenge method to persist. An empty method defaults to plain.
+func normalizeCodeChallenge(challenge, method string) (codeChallengeMethod, error) {
+	m := codeChallengeMethod(method)
+	if m == "" {
+		m = PlainChallengeMethod
+	}
+
+	if !m.isValid() {
+		return "", errors.New("unsupported code challenge method")
+	}
+
+	if !isValidCodeVerifier(challenge) {
+		return "", errors.New("invalid code challenge")
+	}
+
+	return m, nil
+}
+
+// verifyCodeVerifier determines if the verifier presented at the token endpoint matches
+// the challenge that was stored alongside the authorization code.
+func verifyCodeVerifier(verifier, challenge string, method codeChallengeMethod) bool {
+	if !isValidCodeVerifier(verifier) {
+		return false
+	}
+
+	var computed string
+	switch method {
+	case PlainChallengeMethod:
+		computed = verifier
+	case S256ChallengeMethod:
+		sum := sha256.Sum256([]byte(verifier))
+		computed = base64.RawURLEncoding.EncodeToString(sum[:])
+	default:
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_verifyCodeVerifier(t *testing.T) {
+	// Test vector taken from appendix B of RFC 7636.
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		method    codeChallengeMethod
+		want      bool
+	}{
+		{
+			name:      "S256 - match",
+			verifier:  verifier,
+			challenge: challenge,
+			method:    S256ChallengeMethod,
+			want:      true,
+		},
+		{
+			name:      "S256 - mismatch",
+			verifier:  strings.Repeat("a", 43),
+			challenge: challenge,
+			method:    S256ChallengeMethod,
+			want:      false,
+		},
+		{
+			name:      "Plain - match",
+			verifier:  verifier,
+			challenge: verifier,
+			method:    PlainChallengeMethod,
+			want:      true,
+		},
+		{
+			name:      "Plain - mismatch",
+			verifier:  verifier,
+			challenge: challenge,
+			method:    PlainChallengeMethod,
+			want:      false,
+		},
+		{
+			name:      "Verifier too short",
+			verifier:  "abc",
+			challenge: "abc",
+			method:    PlainChallengeMethod,
+			want:      false,
+		},
+		{
+			name:      "Verifier contains invalid characters",
+			verifier:  strings.Repeat("a", 42) + "+",
+			challenge: strings.Repeat("a", 42) + "+",
+			method:    PlainChallengeMethod,
+			want:      false,
+		},
+		{
+			name:      "Unknown method",
+			verifier:  verifier,
+			challenge: verifier,
+			method:    "S512",
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyCodeVerifier(tt.verifier, tt.challenge, tt.method); got != tt.want {
+				t.Errorf("verifyCodeVerifier() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_normalizeCodeChallenge(t *testing.T) {
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	tests := []struct {
+		name      string
+		challenge string
+		method    string
+		want      codeChallengeMethod
+		wantErr   bool
+	}{
+		{
+			name:      "Default method is plain",
+			challenge: challenge,
+			method:    "",
+			want:      PlainChallengeMethod,
+		},
+		{
+			name:      "S256",
+			challenge: challenge,
+			method:    "S256",
+			want:      S256ChallengeMethod,
+		},
+		{
+			name:      "Unknown method",
+			challenge: challenge,
+			method:    "s256",
+			wantErr:   true,
+		},
+		{
+			name:      "Malformed challenge",
+			challenge: "short",
+			method:    "S256",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeCodeChallenge(tt.challenge, tt.method)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("normalizeCodeChallenge() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("normalizeCodeChallenge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/mattmeyers/heimdall/crypto"
+)
+
+const (
+	refreshTokenLength    = 32
+	refreshFamilyIDLength = 16
+)
+
+// RefreshTokenSettings are the available configuration values for issuing refresh tokens.
+type RefreshTokenSettings struct {
+	// Lifespan is the number of seconds a refresh token can be exchanged for. Using a
+	// refresh token issues a new one, so an active session can outlive this value.
+	Lifespan int
+}
+
+func (s RefreshTokenSettings) validate() error {
+	if s.Lifespan <= 0 {
+		return errors.New("refresh token lifetime must be a positive integer")
+	}
+
+	return nil
+}
+
+func generateRefreshToken() (string, error) {
+	return crypto.GenerateRandHexString(refreshTokenLength)
+}
+
+func generateRefreshFamilyID() (string, error) {
+	return crypto.GenerateRandHexString(refreshFamilyIDLength)
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mattmeyers/heimdall/store"
+)
+
+// The token type hints a client can provide when revoking a token (RFC 7009 section 2.1).
+const (
+	AccessTokenHint  = "access_token"
+	RefreshTokenHint = "refresh_token"
+)
+
+var (
+	// ErrTokenRevoked is returned when validating an access token that has been revoked.
+	ErrTokenRevoked = errors.New("token has been revoked")
+	// ErrTokenClientMismatch is returned when a client attempts to revoke a token that was
+	// issued to a different client (RFC 7009 section 2.1).
+	ErrTokenClientMismatch = newError(UnauthorizedClient, "token was not issued to the client")
+)
+
+// Revoke revokes an access or refresh token issued to the authenticated client. Revoking a
+// refresh token revokes its entire family. The hint only determines which token type is
+// tried first. Unknown, invalid, and expired tokens are ignored since there is nothing
+// left to revoke. Tokens issued by Login are not bound to a client, so they are revoked by
+// their user with RevokeUserTokens instead.
+func (s *Service) Revoke(ctx context.Context, token, tokenTypeHint string, ca ClientAuth) error {
+	c, err := s.authenticateClient(ctx, ca)
+	if err != nil {
+		return err
+	}
+
+	revokers := []func(context.Context, string, string) (bool, error){
+		s.revokeAccessToken,
+		s.revokeRefreshToken,
+	}
+	if tokenTypeHint == RefreshTokenHint {
+		revokers[0], revokers[1] = revokers[1], revokers[0]
+	}
+
+	for _, revoke := range revokers {
+		found, err := revoke(ctx, token, c.ClientID)
+		if err != nil || found {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revokeAccessToken records the access token's ID as revoked until the token expires. The
+// returned bool reports whether the token was recognized as an access token.
+func (s *Service) revokeAccessToken(ctx context.Context, token, clientID string) (bool, error) {
+	claims, err := validateJWT(token, s.jwtSettings)
+	if errors.Is(err, ErrTokenExpired) {
+		return true, nil
+	} else if err != nil {
+		return false, nil
+	}
+
+	if claims.ClientID != clientID {
+		return true, ErrTokenClientMismatch
+	}
+
+	return true, s.revokeAccessTokenID(ctx, claims.ID, claims.ExpiresAt.Time)
+}
+
+// revokeAccessTokenID records the access token ID as revoked until the token expires.
+func (s *Service) revokeAccessTokenID(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	// Tokens are accepted for up to ClockSkew seconds after they expire, so the record must
+	// outlive the token by the same amount.
+	skew := time.Duration(s.jwtSettings.ClockSkew) * time.Second
+
+	return s.revokedTokenStore.Insert(ctx, store.RevokedToken{
+		TokenID:   tokenID,
+		RevokedAt: time.Now(),
+		ExpiresAt: expiresAt.Add(skew),
+	})
+}
+
+// revokeRefreshToken revokes the refresh token's family. The returned bool reports whether
+// the token was recognized as a refresh token.
+func (s *Service) revokeRefreshToken(ctx context.Context, token, clientID string) (bool, error) {
+	rt, err := s.refreshTokenStore.GetByToken(ctx, token)
+	if err != nil {
+		return false, nil
+	}
+
+	if rt.ClientID != clientID {
+		return true, ErrTokenClientMismatch
+	}
+
+	return true, s.refreshTokenStore.RevokeFamily(ctx, rt.FamilyID)
+}
+
+// RevokeUserTokens revokes every access and refresh token the token's user obtained by
+// signing in directly, including the token itself, so that a user can sign out of every
+// device. Tokens issued to clients are not affected, and are revoked by revoking the user's
+// consent instead.
+func (s *Service) RevokeUserTokens(ctx context.Context, token string) error {
+	userID, err := s.authenticateUserToken(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	if err = s.userStore.RevokeTokens(ctx, userID, time.Now()); err != nil {
+		return err
+	}
+
+	return s.refreshTokenStore.RevokeClient(ctx, userID, "")
+}
+
+// RunPruner periodically deletes revoked token records whose tokens have expired and
+// consent requests that were never answered. It blocks until the context is canceled.
+func (s *Service) RunPruner(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+
+		if err := s.revokedTokenStore.DeleteExpired(ctx, now); err != nil {
+			onError(err)
+		}
+
+		if err := s.consentStore.DeleteExpiredRequests(ctx, now.Add(-consentRequestLifespan)); err != nil {
+			onError(err)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mattmeyers/heimdall/store"
+)
+
+func TestService_Revoke(t *testing.T) {
+	clients := clientStoreStub{clients: map[string]store.Client{
+		"client": {ClientID: "client", Type: store.PublicClient, TokenEndpointAuthMethod: store.ClientAuthNone},
+		"other":  {ClientID: "other", Type: store.PublicClient, TokenEndpointAuthMethod: store.ClientAuthNone},
+	}}
+
+	accessToken := func(clientID string) string {
+		token, err := generateJWT(testJWTSettings, accessTokenParams{Subject: "1", ClientID: clientID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token.AccessToken
+	}
+
+	tests := []struct {
+		name              string
+		token             string
+		hint              string
+		wantCode          ErrorCode
+		wantRevokedAccess bool
+		wantRevokedFamily string
+	}{
+		{
+			name:              "Access token",
+			token:             accessToken("client"),
+			wantRevokedAccess: true,
+		},
+		{
+			name:              "Access token with refresh token hint",
+			token:             accessToken("client"),
+			hint:              RefreshTokenHint,
+			wantRevokedAccess: true,
+		},
+		{
+			name:              "Refresh token",
+			token:             "refresh",
+			wantRevokedFamily: "family",
+		},
+		{
+			name:     "Another client's access token",
+			token:    accessToken("other"),
+			wantCode: UnauthorizedClient,
+		},
+		{
+			name:     "Another client's refresh token",
+			token:    "other-refresh",
+			wantCode: UnauthorizedClient,
+		},
+		{
+			name:  "Unknown token",
+			token: "unknown",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refreshTokens := &refreshTokenStoreStub{tokens: map[string]store.RefreshToken{
+				"refresh":       {Token: "refresh", FamilyID: "family", UserID: 1, ClientID: "client"},
+				"other-refresh": {Token: "other-refresh", FamilyID: "other-family", UserID: 1, ClientID: "other"},
+			}}
+			s := &Service{
+				userStore:         userStoreStub{1: {ID: 1}},
+				clientStore:       clients,
+				refreshTokenStore: refreshTokens,
+				revokedTokenStore: revokedTokenStoreStub{},
+				jwtSettings:       testJWTSettings,
+			}
+
+			ctx := context.Background()
+			ca := ClientAuth{ClientID: "client", Method: store.ClientAuthNone}
+
+			err := s.Revoke(ctx, tt.token, tt.hint, ca)
+
+			var oauthErr *Error
+			if tt.wantCode == "" && err != nil {
+				t.Fatalf("Revoke() error = %v", err)
+			} else if tt.wantCode != "" && (!errors.As(err, &oauthErr) || oauthErr.Code != tt.wantCode) {
+				t.Fatalf("Revoke() error = %v, want %s", err, tt.wantCode)
+			}
+
+			if _, err = s.ValidateToken(ctx, tt.token); errors.Is(err, ErrTokenRevoked) != tt.wantRevokedAccess {
+				t.Errorf("ValidateToken() error = %v, want revoked %v", err, tt.wantRevokedAccess)
+			}
+
+			var revokedFamily string
+			if len(refreshTokens.revokedFamilies) > 0 {
+				revokedFamily = refreshTokens.revokedFamilies[0]
+			}
+			if revokedFamily != tt.wantRevokedFamily {
+				t.Errorf("revoked family = %q, want %q", revokedFamily, tt.wantRevokedFamily)
+			}
+		})
+	}
+}
+
+func TestService_RevokeUserTokens(t *testing.T) {
+	users := userStoreStub{1: {ID: 1}}
+	refreshTokens := &refreshTokenStoreStub{tokens: map[string]store.RefreshToken{
+		"login":  {Token: "login", UserID: 1},
+		"client": {Token: "client", UserID: 1, ClientID: "client"},
+	}}
+	s := &Service{
+		userStore:         users,
+		clientStore:       clientStoreStub{clients: map[string]store.Client{"client": {ClientID: "client"}}},
+		refreshTokenStore: refreshTokens,
+		revokedTokenStore: revokedTokenStoreStub{},
+		jwtSettings:       testJWTSettings,
+	}
+
+	ctx := context.Background()
+
+	loginToken, err := generateJWT(testJWTSettings, accessTokenParams{Subject: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientToken, err := generateJWT(testJWTSettings, accessTokenParams{Subject: "1", ClientID: "client"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.RevokeUserTokens(ctx, clientToken.AccessToken); !errors.Is(err, ErrInsufficientScope) {
+		t.Errorf("RevokeUserTokens() with a client's token error = %v, want %v", err, ErrInsufficientScope)
+	}
+
+	if err = s.RevokeUserTokens(ctx, loginToken.AccessToken); err != nil {
+		t.Fatalf("RevokeUserTokens() error = %v", err)
+	}
+
+	if _, err = s.ValidateToken(ctx, loginToken.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("ValidateToken() of the login token error = %v, want %v", err, ErrTokenRevoked)
+	}
+
+	if _, err = s.ValidateToken(ctx, clientToken.AccessToken); err != nil {
+		t.Errorf("ValidateToken() of the client's token error = %v, want nil", err)
+	}
+
+	if !refreshTokens.tokens["login"].Revoked || refreshTokens.tokens["client"].Revoked {
+		t.Errorf("refresh tokens = %+v, want only the login token revoked", refreshTokens.tokens)
+	}
+}
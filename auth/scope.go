@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/mattmeyers/heimdall/store"
+)
+
+// The OpenID Connect scopes. OpenIDScope requests an ID token, while the others request
+// access to the matching UserInfo claims.
+const (
+	OpenIDScope  = "openid"
+	EmailScope   = "email"
+	ProfileScope = "profile"
+)
+
+// parseScope splits a space delimited scope parameter into its individual scopes. Duplicate
+// scopes are removed while preserving order.
+func parseScope(scope string) []string {
+	var scopes []string
+	seen := make(map[string]bool)
+	for _, s := range strings.Fields(scope) {
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		scopes = append(scopes, s)
+	}
+
+	return scopes
+}
+
+func formatScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// restrictScopes ensures that every requested scope is contained in the allowed set. If no
+// scopes are requested, the full allowed set is granted.
+func restrictScopes(requested, allowed []string) ([]string, error) {
+	if len(requested) == 0 {
+		return allowed, nil
+	}
+
+	isAllowed := make(map[string]bool, len(allowed))
+	for _, s := range allowed {
+		isAllowed[s] = true
+	}
+
+	for _, s := range requested {
+		if !isAllowed[s] {
+			return nil, errors.New("scope not allowed: " + s)
+		}
+	}
+
+	return requested, nil
+}
+
+// grantScopes determines the scopes granted for the requested scope parameter. The
+// requested scopes must be allowed for the client and registered with the server. The
+// openid scope cannot be granted unless the server can sign ID tokens.
+func (s *Service) grantScopes(ctx context.Context, requested string, client store.Client) ([]string, error) {
+	scopes, err := restrictScopes(parseScope(requested), client.AllowedScopes)
+	if err != nil {
+		return nil, newError(InvalidScope, err.Error())
+	}
+
+	if containsScope(scopes, OpenIDScope) && !s.jwtSettings.signsIDTokens() {
+		return nil, newError(InvalidScope, "the openid scope requires an asymmetric signing key")
+	}
+
+	registered, err := s.registeredScopes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sc := range scopes {
+		if _, ok := registered[sc]; !ok {
+			return nil, newError(InvalidScope, "unknown scope: "+sc)
+		}
+	}
+
+	return scopes, nil
+}
+
+// registeredScopes returns every registered scope keyed by name.
+func (s *Service) registeredScopes(ctx context.Context) (map[string]store.Scope, error) {
+	scopes, err := s.scopeStore.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	registered := make(map[string]store.Scope, len(scopes))
+	for _, sc := range scopes {
+		registered[sc.Name] = sc
+	}
+
+	return registered, nil
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mattmeyers/heimdall/store"
+)
+
+func Test_parseScope(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope string
+		want  []string
+	}{
+		{
+			name:  "Empty",
+			scope: "",
+			want:  nil,
+		},
+		{
+			name:  "Multiple scopes",
+			scope: "read  write",
+			want:  []string{"read", "write"},
+		},
+		{
+			name:  "Duplicates removed",
+			scope: "read write read",
+			want:  []string{"read", "write"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseScope(tt.scope); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseScope() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_restrictScopes(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested []string
+		allowed   []string
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name:      "No scopes requested grants allowed set",
+			requested: nil,
+			allowed:   []string{"read", "write"},
+			want:      []string{"read", "write"},
+		},
+		{
+			name:      "Subset of allowed",
+			requested: []string{"write"},
+			allowed:   []string{"read", "write"},
+			want:      []string{"write"},
+		},
+		{
+			name:      "Scope not allowed",
+			requested: []string{"read", "admin"},
+			allowed:   []string{"read", "write"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := restrictScopes(tt.requested, tt.allowed)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("restrictScopes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("restrictScopes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_grantScopes(t *testing.T) {
+	scopes := scopeStoreStub{{Name: "openid"}, {Name: "read"}, {Name: "write"}}
+
+	tests := []struct {
+		name      string
+		requested string
+		allowed   []string
+		algorithm signingAlgorithm
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name:      "Registered and allowed",
+			requested: "read",
+			allowed:   []string{"read", "write"},
+			want:      []string{"read"},
+		},
+		{
+			name:      "Defaults to allowed",
+			requested: "",
+			allowed:   []string{"read", "write"},
+			want:      []string{"read", "write"},
+		},
+		{
+			name:      "Not allowed",
+			requested: "write",
+			allowed:   []string{"read"},
+			wantErr:   true,
+		},
+		{
+			name:      "Allowed but not registered",
+			requested: "admin",
+			allowed:   []string{"read", "admin"},
+			wantErr:   true,
+		},
+		{
+			name:      "OpenID with an asymmetric key",
+			requested: "openid read",
+			allowed:   []string{"openid", "read"},
+			algorithm: ECDSA256Algorithm,
+			want:      []string{"openid", "read"},
+		},
+		{
+			name:      "OpenID with HS256",
+			requested: "openid read",
+			allowed:   []string{"openid", "read"},
+			algorithm: HMAC256Algorithm,
+			wantErr:   true,
+		},
+		{
+			name:      "Defaults to allowed OpenID with HS256",
+			requested: "",
+			allowed:   []string{"openid", "read"},
+			algorithm: HMAC256Algorithm,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{scopeStore: scopes, jwtSettings: JWTSettings{Algorithm: tt.algorithm}}
+			got, err := s.grantScopes(context.Background(), tt.requested, store.Client{AllowedScopes: tt.allowed})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("grantScopes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("grantScopes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
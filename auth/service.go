@@ -6,6 +6,7 @@ import (
 	"embed"
 	"errors"
 	"html/template"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,21 +20,47 @@ var templateFS embed.FS
 var templates = template.Must(template.ParseFS(templateFS, "templates/*"))
 
 type Service struct {
-	userStore     store.UserStore
-	clientStore   store.ClientStore
-	authCodeStore store.AuthCodeStore
-	jwtSettings   JWTSettings
+	userStore            store.UserStore
+	clientStore          store.ClientStore
+	authCodeStore        store.AuthCodeStore
+	refreshTokenStore    store.RefreshTokenStore
+	revokedTokenStore    store.RevokedTokenStore
+	scopeStore           store.ScopeStore
+	consentStore         store.ConsentStore
+	jwtSettings          JWTSettings
+	refreshTokenSettings RefreshTokenSettings
+	authCodeSettings     AuthCodeSettings
 }
 
 func NewService(userStore store.UserStore,
 	clientStore store.ClientStore,
 	authCodeStore store.AuthCodeStore,
-	jwtSettings JWTSettings) (*Service, error) {
+	refreshTokenStore store.RefreshTokenStore,
+	revokedTokenStore store.RevokedTokenStore,
+	scopeStore store.ScopeStore,
+	consentStore store.ConsentStore,
+	jwtSettings JWTSettings,
+	refreshTokenSettings RefreshTokenSettings,
+	authCodeSettings AuthCodeSettings) (*Service, error) {
+	if err := refreshTokenSettings.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := authCodeSettings.validate(); err != nil {
+		return nil, err
+	}
+
 	return &Service{
-		userStore:     userStore,
-		clientStore:   clientStore,
-		authCodeStore: authCodeStore,
-		jwtSettings:   jwtSettings}, nil
+		userStore:            userStore,
+		clientStore:          clientStore,
+		authCodeStore:        authCodeStore,
+		refreshTokenStore:    refreshTokenStore,
+		revokedTokenStore:    revokedTokenStore,
+		scopeStore:           scopeStore,
+		consentStore:         consentStore,
+		jwtSettings:          jwtSettings,
+		refreshTokenSettings: refreshTokenSettings,
+		authCodeSettings:     authCodeSettings}, nil
 }
 
 func (s *Service) Register(ctx context.Context, email, password string) error {
@@ -59,31 +86,82 @@ func (s *Service) Register(ctx context.Context, email, password string) error {
 }
 
 func (s *Service) Login(ctx context.Context, email, password string) (Token, error) {
-	u, err := s.userStore.GetByEmail(ctx, email)
+	u, err := s.authenticateUser(ctx, email, password)
 	if err != nil {
 		return Token{}, err
 	}
 
+	return s.issueTokens(ctx, u.ID, "", store.Client{}, accessTokenParams{AuthTime: time.Now()})
+}
+
+func (s *Service) authenticateUser(ctx context.Context, email, password string) (store.User, error) {
+	u, err := s.userStore.GetByEmail(ctx, email)
+	if err != nil {
+		return store.User{}, err
+	}
+
 	valid, err := crypto.ValidatePassword(password, u.Hash)
 	if err != nil {
-		return Token{}, err
+		return store.User{}, err
 	}
 
 	if !valid {
-		return Token{}, errors.New("invalid password")
+		return store.User{}, errors.New("invalid password")
+	}
+
+	return u, nil
+}
+
+// ValidateToken validates the access token and returns its claims. Tokens that have been
+// revoked, were issued to a client that has since been disabled or deleted, or were revoked
+// by their user with RevokeUserTokens are rejected with ErrTokenRevoked.
+func (s *Service) ValidateToken(ctx context.Context, token string) (TokenClaims, error) {
+	claims, err := validateJWT(token, s.jwtSettings)
+	if err != nil {
+		return TokenClaims{}, err
+	}
+
+	revoked, err := s.revokedTokenStore.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return TokenClaims{}, err
+	} else if revoked || !s.tokenOwnerActive(ctx, claims) {
+		return TokenClaims{}, ErrTokenRevoked
+	}
+
+	return claims.toTokenClaims(), nil
+}
+
+// tokenOwnerActive determines if the token can still be used by its owner. Tokens issued to
+// a client are rejected once the client is disabled or deleted. Tokens issued by Login are
+// not bound to a client, and are rejected once the user revokes them.
+func (s *Service) tokenOwnerActive(ctx context.Context, claims accessTokenClaims) bool {
+	if claims.ClientID != "" {
+		c, err := s.clientStore.GetByClientID(ctx, claims.ClientID)
+		return err == nil && !c.Disabled
+	}
+
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return false
+	}
+
+	u, err := s.userStore.GetByID(ctx, id)
+	if err != nil {
+		return false
 	}
 
-	return generateJWT(s.jwtSettings)
+	return claims.IssuedAt != nil && claims.IssuedAt.After(u.TokensRevokedAt)
 }
 
-func (s *Service) ValidateToken(ctx context.Context, token string) error {
-	return validateJWT(token, s.jwtSettings)
+// PublicKeys returns the JWK Set containing every public key that can verify issued tokens.
+func (s *Service) PublicKeys(ctx context.Context) JWKSet {
+	return s.jwtSettings.publicKeys()
 }
 
 func (s *Service) validateRedirectURL(ctx context.Context, clientID, redirectURL string) error {
 	c, err := s.clientStore.GetByClientID(ctx, clientID)
-	if err != nil {
-		return err
+	if err != nil || c.Disabled {
+		return ErrUnknownClient
 	}
 
 	for _, u := range c.RedirectURLs {
@@ -92,15 +170,31 @@ func (s *Service) validateRedirectURL(ctx context.Context, clientID, redirectURL
 		}
 	}
 
-	return errors.New("invalid redirect URL")
+	return ErrInvalidRedirectURL
 }
 
-func (s *Service) AuthCodeFlow(ctx context.Context, clientID, redirectURL string) ([]byte, error) {
+// AuthCodeFlow renders the sign in page presented to the resource owner at the start of the
+// authorization code flow.
+func (s *Service) AuthCodeFlow(ctx context.Context, req AuthCodeRequest) ([]byte, error) {
+	req, err := s.validateAuthCodeRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
 	buf := &bytes.Buffer{}
-	err := templates.ExecuteTemplate(
+	err = templates.ExecuteTemplate(
 		buf,
 		"auth_code_flow.html",
-		map[string]interface{}{"clientID": clientID, "redirectURL": redirectURL},
+		map[string]interface{}{
+			"responseType":        req.ResponseType,
+			"clientID":            req.ClientID,
+			"redirectURL":         req.RedirectURL,
+			"state":               req.State,
+			"scope":               req.Scope,
+			"nonce":               req.Nonce,
+			"codeChallenge":       req.CodeChallenge,
+			"codeChallengeMethod": req.CodeChallengeMethod,
+		},
 	)
 	if err != nil {
 		return nil, err
@@ -109,22 +203,507 @@ func (s *Service) AuthCodeFlow(ctx context.Context, clientID, redirectURL string
 	return buf.Bytes(), nil
 }
 
-func (s *Service) ConvertCodeToToken(ctx context.Context, code, clientID, clientSecret, redirectURL string) (Token, error) {
-	client, err := s.clientStore.GetByClientID(ctx, clientID)
+// GrantAuthCode authenticates the resource owner and issues an authorization code bound to
+// the requesting client and redirect URL. If the request includes scopes the user has not
+// yet consented to, the consent screen is returned instead of a code.
+func (s *Service) GrantAuthCode(ctx context.Context, email, password string, req AuthCodeRequest) (AuthCodeGrant, error) {
+	req, err := s.validateAuthCodeRequest(ctx, req)
+	if err != nil {
+		return AuthCodeGrant{}, err
+	}
+
+	u, err := s.authenticateUser(ctx, email, password)
+	if err != nil {
+		return AuthCodeGrant{}, err
+	}
+	authTime := time.Now()
+
+	required, err := s.consentRequired(ctx, u.ID, req.ClientID, parseScope(req.Scope))
+	if err != nil {
+		return AuthCodeGrant{}, err
+	}
+
+	if required {
+		page, err := s.requestConsent(ctx, u.ID, authTime, req)
+		if err != nil {
+			return AuthCodeGrant{}, err
+		}
+
+		return AuthCodeGrant{ConsentPage: page}, nil
+	}
+
+	code, err := s.issueAuthCode(ctx, u.ID, authTime, req)
+	if err != nil {
+		return AuthCodeGrant{}, err
+	}
+
+	return AuthCodeGrant{Code: code}, nil
+}
+
+// issueAuthCode stores a new auth code for the request. The auth time is when the user
+// signed in, which may be well before the code is issued if the user was asked for consent.
+func (s *Service) issueAuthCode(ctx context.Context, userID int, authTime time.Time, req AuthCodeRequest) (string, error) {
+	code := store.AuthCode{
+		UserID:              userID,
+		ClientID:            req.ClientID,
+		RedirectURL:         req.RedirectURL,
+		Scope:               req.Scope,
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		AuthTime:            authTime,
+		CreatedAt:           time.Now(),
+	}
+
+	var err error
+	if code.Code, err = generateAuthCode(); err != nil {
+		return "", err
+	}
+
+	if _, err = s.authCodeStore.Insert(ctx, code); err != nil {
+		return "", err
+	}
+
+	return code.Code, nil
+}
+
+// validateAuthCodeRequest ensures the redirect URL is registered to the client and that the
+// response type, scopes and PKCE parameters are acceptable. The returned request has its
+// granted scopes and normalized challenge method.
+func (s *Service) validateAuthCodeRequest(ctx context.Context, req AuthCodeRequest) (AuthCodeRequest, error) {
+	if err := s.validateRedirectURL(ctx, req.ClientID, req.RedirectURL); err != nil {
+		return AuthCodeRequest{}, err
+	}
+
+	switch req.ResponseType {
+	case CodeResponseType:
+	case "":
+		return AuthCodeRequest{}, newError(InvalidRequest, "missing response_type")
+	default:
+		return AuthCodeRequest{}, newError(UnsupportedResponseType, "unsupported response_type: "+req.ResponseType)
+	}
+
+	client, err := s.clientStore.GetByClientID(ctx, req.ClientID)
+	if err != nil {
+		return AuthCodeRequest{}, err
+	}
+
+	if !clientAllows(client.ResponseTypes, req.ResponseType) {
+		return AuthCodeRequest{}, newError(UnauthorizedClient, "client is not allowed to use response_type "+req.ResponseType)
+	}
+
+	scopes, err := s.grantScopes(ctx, req.Scope, client)
+	if err != nil {
+		return AuthCodeRequest{}, err
+	}
+	req.Scope = formatScope(scopes)
+
+	if req.CodeChallenge != "" {
+		method, err := normalizeCodeChallenge(req.CodeChallenge, req.CodeChallengeMethod)
+		if err != nil {
+			return AuthCodeRequest{}, newError(InvalidRequest, err.Error())
+		}
+		req.CodeChallengeMethod = string(method)
+	} else if client.RequirePKCE {
+		return AuthCodeRequest{}, newError(InvalidRequest, "code challenge required")
+	} else if req.CodeChallengeMethod != "" {
+		return AuthCodeRequest{}, newError(InvalidRequest, "code challenge method provided without a code challenge")
+	}
+
+	return req, nil
+}
+
+func (s *Service) ConvertCodeToToken(ctx context.Context, code string, ca ClientAuth, redirectURL, codeVerifier string) (Token, error) {
+	client, err := s.authenticateClient(ctx, ca)
 	if err != nil {
 		return Token{}, err
 	}
 
-	if client.ClientSecret != clientSecret {
-		return Token{}, errors.New("incorrect secret")
+	if err = requireGrantType(client, AuthorizationCodeGrant); err != nil {
+		return Token{}, err
 	}
 
 	codeObj, err := s.authCodeStore.GetByCode(ctx, code)
+	if err != nil {
+		return Token{}, newError(InvalidGrant, "unknown auth code")
+	}
+
+	if codeObj.ClientID != client.ClientID {
+		return Token{}, newError(InvalidGrant, "auth code was not issued to this client")
+	}
+
+	// An auth code may only be used once. If it is presented again, every token that was
+	// issued with it is revoked (RFC 6749 section 4.1.2).
+	if codeObj.Consumed {
+		return Token{}, s.revokeAuthCodeTokens(ctx, codeObj)
+	}
+
+	if time.Now().After(codeObj.CreatedAt.Add(time.Second * time.Duration(s.authCodeLifespan(client)))) {
+		return Token{}, newError(InvalidGrant, "auth code has expired")
+	}
+
+	if codeObj.RedirectURL != redirectURL {
+		return Token{}, newError(InvalidGrant, "redirect URL does not match authorization request")
+	}
+
+	if err := verifyPKCE(client, codeObj, codeVerifier); err != nil {
+		return Token{}, newError(InvalidGrant, err.Error())
+	}
+
+	familyID, err := generateRefreshFamilyID()
+	if err != nil {
+		return Token{}, err
+	}
+
+	scopes := parseScope(codeObj.Scope)
+	params := accessTokenParams{
+		Subject:  strconv.Itoa(codeObj.UserID),
+		ClientID: client.ClientID,
+		Scopes:   scopes,
+		AuthTime: codeObj.AuthTime,
+	}
+
+	// The access token is generated before the code is consumed so that it can be recorded
+	// with the code and revoked if the code is replayed.
+	token, err := generateJWT(s.clientJWTSettings(client), params)
 	if err != nil {
 		return Token{}, err
-	} else if time.Now().After(codeObj.CreatedAt.Add(3600 * time.Second)) {
-		return Token{}, errors.New("access code has expired")
 	}
 
-	return generateJWT(s.jwtSettings)
+	err = s.authCodeStore.Consume(ctx, codeObj.ID, familyID, token.ID, token.ExpiresAt)
+	if errors.Is(err, store.ErrAuthCodeUsed) {
+		// The code was redeemed concurrently. Reload it to find the issued tokens.
+		if codeObj, err = s.authCodeStore.GetByCode(ctx, code); err != nil {
+			return Token{}, err
+		}
+		return Token{}, s.revokeAuthCodeTokens(ctx, codeObj)
+	} else if err != nil {
+		return Token{}, err
+	}
+
+	// A refresh token is only issued if the client is allowed to use it.
+	if clientAllows(client.GrantTypes, RefreshTokenGrant) {
+		token.RefreshToken, err = s.issueRefreshToken(ctx, codeObj.UserID, familyID, client, params)
+		if err != nil {
+			return Token{}, err
+		}
+	}
+
+	if containsScope(scopes, OpenIDScope) {
+		token.IDToken, err = generateIDToken(s.clientJWTSettings(client), idTokenParams{
+			Subject:     strconv.Itoa(codeObj.UserID),
+			ClientID:    client.ClientID,
+			Nonce:       codeObj.Nonce,
+			AuthTime:    codeObj.AuthTime,
+			AccessToken: token.AccessToken,
+		})
+		if err != nil {
+			return Token{}, err
+		}
+	}
+
+	return token, nil
+}
+
+// revokeAuthCodeTokens revokes the refresh token family and access token issued when the
+// code was first redeemed. Codes consumed before the access token was recorded only have
+// their refresh tokens revoked.
+func (s *Service) revokeAuthCodeTokens(ctx context.Context, code store.AuthCode) error {
+	if err := s.refreshTokenStore.RevokeFamily(ctx, code.TokenFamilyID); err != nil {
+		return err
+	}
+
+	if code.AccessTokenID != "" {
+		if err := s.revokeAccessTokenID(ctx, code.AccessTokenID, code.AccessTokenExpiresAt); err != nil {
+			return err
+		}
+	}
+
+	return newError(InvalidGrant, "auth code already used")
+}
+
+// RefreshToken exchanges a refresh token for a new access and refresh token pair. The used
+// refresh token is invalidated. Presenting an already used refresh token is treated as a
+// sign of theft, and every token in its family is revoked. If scope is set, the access token
+// is limited to those scopes, which must all have been granted to the refresh token. The new
+// refresh token always keeps the original scopes (RFC 6749 section 6).
+func (s *Service) RefreshToken(ctx context.Context, refreshToken string, ca ClientAuth, scope string) (Token, error) {
+	rt, err := s.refreshTokenStore.GetByToken(ctx, refreshToken)
+	if err != nil {
+		return Token{}, newError(InvalidGrant, "unknown refresh token")
+	}
+
+	if rt.ClientID != ca.ClientID {
+		return Token{}, newError(InvalidGrant, "refresh token was not issued to this client")
+	}
+
+	// Refresh tokens issued by Login are not bound to a client.
+	var client store.Client
+	if rt.ClientID != "" {
+		if client, err = s.authenticateClient(ctx, ca); err != nil {
+			return Token{}, err
+		}
+
+		if err = requireGrantType(client, RefreshTokenGrant); err != nil {
+			return Token{}, err
+		}
+	}
+
+	if rt.Revoked {
+		return Token{}, newError(InvalidGrant, "refresh token has been revoked")
+	}
+
+	if rt.Used {
+		return Token{}, s.revokeRefreshTokenFamily(ctx, rt.FamilyID)
+	}
+
+	if time.Now().After(rt.ExpiresAt) {
+		return Token{}, newError(InvalidGrant, "refresh token has expired")
+	}
+
+	scopes, err := restrictScopes(parseScope(scope), parseScope(rt.Scope))
+	if err != nil {
+		return Token{}, newError(InvalidScope, err.Error())
+	}
+
+	err = s.refreshTokenStore.MarkUsed(ctx, rt.ID)
+	if errors.Is(err, store.ErrRefreshTokenUsed) {
+		return Token{}, s.revokeRefreshTokenFamily(ctx, rt.FamilyID)
+	} else if err != nil {
+		return Token{}, err
+	}
+
+	params := accessTokenParams{
+		Subject:  strconv.Itoa(rt.UserID),
+		ClientID: rt.ClientID,
+		Scopes:   scopes,
+		AuthTime: rt.AuthTime,
+	}
+
+	token, err := generateJWT(s.clientJWTSettings(client), params)
+	if err != nil {
+		return Token{}, err
+	}
+
+	params.Scopes = parseScope(rt.Scope)
+	if token.RefreshToken, err = s.issueRefreshToken(ctx, rt.UserID, rt.FamilyID, client, params); err != nil {
+		return Token{}, err
+	}
+
+	return token, nil
+}
+
+// ClientCredentials issues an access token to a client acting on its own behalf. The token's
+// subject is the client itself, and the requested scopes must be registered and allowed for
+// the client. Public clients cannot use this grant since they cannot authenticate.
+func (s *Service) ClientCredentials(ctx context.Context, ca ClientAuth, scope string) (Token, error) {
+	client, err := s.authenticateClient(ctx, ca)
+	if err != nil {
+		return Token{}, err
+	} else if client.Type == store.PublicClient {
+		return Token{}, newError(UnauthorizedClient, "public clients cannot use the client_credentials grant")
+	}
+
+	if err = requireGrantType(client, ClientCredentialsGrant); err != nil {
+		return Token{}, err
+	}
+
+	scopes, err := s.grantScopes(ctx, scope, client)
+	if err != nil {
+		return Token{}, err
+	}
+
+	return generateJWT(s.clientJWTSettings(client), accessTokenParams{
+		Subject:  client.ClientID,
+		ClientID: client.ClientID,
+		Scopes:   scopes,
+	})
+}
+
+// ErrInvalidClient is returned when a client cannot be authenticated.
+var ErrInvalidClient = newError(InvalidClient, "client authentication failed")
+
+// ClientAuth holds the credentials a client presented and the method used to present them.
+type ClientAuth struct {
+	ClientID     string
+	ClientSecret string
+	Method       store.ClientAuthMethod
+}
+
+// authenticateClient verifies the client's credentials. Clients must authenticate using the
+// method they were registered with, and disabled clients cannot authenticate. Public clients
+// have no secret and are identified only by their client_id. After a secret rotation, the
+// previous secret is accepted until its grace period ends.
+func (s *Service) authenticateClient(ctx context.Context, ca ClientAuth) (store.Client, error) {
+	client, err := s.clientStore.GetByClientID(ctx, ca.ClientID)
+	if err != nil || client.Disabled {
+		return store.Client{}, ErrInvalidClient
+	}
+
+	if client.TokenEndpointAuthMethod != ca.Method {
+		return store.Client{}, ErrInvalidClient
+	}
+
+	if ca.Method == store.ClientAuthNone {
+		if client.Type != store.PublicClient {
+			return store.Client{}, ErrInvalidClient
+		}
+		return client, nil
+	}
+
+	if !clientSecretMatches(client, ca.ClientSecret, time.Now()) {
+		return store.Client{}, ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+// requireGrantType returns an unauthorized_client error if the client is not allowed to use
+// the grant type.
+func requireGrantType(client store.Client, grantType string) error {
+	if !clientAllows(client.GrantTypes, grantType) {
+		return newError(UnauthorizedClient, "client is not allowed to use the "+grantType+" grant")
+	}
+
+	return nil
+}
+
+// clientAllows determines if v is one of the client's allowed grant or response types.
+func clientAllows(allowed []string, v string) bool {
+	for _, a := range allowed {
+		if a == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientSecretMatches determines if the secret is the client's current secret, or its
+// previous secret if that has not yet expired.
+func clientSecretMatches(client store.Client, secret string, now time.Time) bool {
+	if valid, err := crypto.ValidatePassword(secret, client.SecretHash); err == nil && valid {
+		return true
+	}
+
+	if client.PreviousSecretHash == "" || !now.Before(client.PreviousSecretExpiresAt) {
+		return false
+	}
+
+	valid, err := crypto.ValidatePassword(secret, client.PreviousSecretHash)
+	return err == nil && valid
+}
+
+func (s *Service) revokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	if err := s.refreshTokenStore.RevokeFamily(ctx, familyID); err != nil {
+		return err
+	}
+
+	return newError(InvalidGrant, "refresh token reuse detected")
+}
+
+// issueTokens generates an access token for the user along with a refresh token, using the
+// client's lifespans. If familyID is empty, the refresh token starts a new family.
+func (s *Service) issueTokens(ctx context.Context, userID int, familyID string, client store.Client, p accessTokenParams) (Token, error) {
+	p.Subject = strconv.Itoa(userID)
+
+	token, err := generateJWT(s.clientJWTSettings(client), p)
+	if err != nil {
+		return Token{}, err
+	}
+
+	if familyID == "" {
+		if familyID, err = generateRefreshFamilyID(); err != nil {
+			return Token{}, err
+		}
+	}
+
+	if token.RefreshToken, err = s.issueRefreshToken(ctx, userID, familyID, client, p); err != nil {
+		return Token{}, err
+	}
+
+	return token, nil
+}
+
+// issueRefreshToken stores a new refresh token in the family with the same client, scopes
+// and auth time as the access token described by p.
+func (s *Service) issueRefreshToken(ctx context.Context, userID int, familyID string, client store.Client, p accessTokenParams) (string, error) {
+	rt := store.RefreshToken{
+		FamilyID: familyID,
+		UserID:   userID,
+		ClientID: p.ClientID,
+		Scope:    formatScope(p.Scopes),
+		AuthTime: p.AuthTime,
+	}
+
+	var err error
+	if rt.Token, err = generateRefreshToken(); err != nil {
+		return "", err
+	}
+
+	rt.CreatedAt = time.Now()
+	rt.ExpiresAt = rt.CreatedAt.Add(time.Second * time.Duration(s.refreshTokenLifespan(client)))
+
+	if _, err = s.refreshTokenStore.Insert(ctx, rt); err != nil {
+		return "", err
+	}
+
+	return rt.Token, nil
+}
+
+// clientJWTSettings returns the JWT settings with the client's access and ID token lifespans
+// applied.
+func (s *Service) clientJWTSettings(client store.Client) JWTSettings {
+	settings := s.jwtSettings
+	if client.TokenLifespans.AccessToken > 0 {
+		settings.Lifespan = client.TokenLifespans.AccessToken
+	}
+
+	if client.TokenLifespans.IDToken > 0 {
+		settings.IDTokenLifespan = client.TokenLifespans.IDToken
+	}
+
+	return settings
+}
+
+// refreshTokenLifespan is the number of seconds a refresh token issued to the client is valid.
+func (s *Service) refreshTokenLifespan(client store.Client) int {
+	if client.TokenLifespans.RefreshToken > 0 {
+		return client.TokenLifespans.RefreshToken
+	}
+
+	return s.refreshTokenSettings.Lifespan
+}
+
+// authCodeLifespan is the number of seconds an auth code issued to the client is valid.
+func (s *Service) authCodeLifespan(client store.Client) int {
+	if client.TokenLifespans.AuthCode > 0 {
+		return client.TokenLifespans.AuthCode
+	}
+
+	return s.authCodeSettings.Lifespan
+}
+
+// verifyPKCE checks the code verifier presented at the token endpoint against the challenge
+// stored with the auth code. A verifier must be provided if and only if a challenge was.
+func verifyPKCE(client store.Client, code store.AuthCode, codeVerifier string) error {
+	if code.CodeChallenge == "" {
+		if client.RequirePKCE {
+			return errors.New("code challenge required")
+		} else if codeVerifier != "" {
+			return errors.New("unexpected code verifier")
+		}
+
+		return nil
+	}
+
+	if codeVerifier == "" {
+		return errors.New("code verifier required")
+	}
+
+	if !verifyCodeVerifier(codeVerifier, code.CodeChallenge, codeChallengeMethod(code.CodeChallengeMethod)) {
+		return errors.New("invalid code verifier")
+	}
+
+	return nil
 }
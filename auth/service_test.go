@@ -0,0 +1,655 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mattmeyers/heimdall/crypto"
+	"github.com/mattmeyers/heimdall/store"
+)
+
+// testJWTSettings are valid settings for signing tokens in tests.
+var testJWTSettings = JWTSettings{
+	Issuer:     "Heimdall",
+	Lifespan:   60,
+	SigningKey: "secretkey",
+	Algorithm:  HMAC256Algorithm,
+}
+
+// clientStoreStub holds clients keyed by client ID.
+type clientStoreStub struct {
+	store.ClientStore
+	clients map[string]store.Client
+}
+
+func (s clientStoreStub) GetByClientID(ctx context.Context, id string) (store.Client, error) {
+	c, ok := s.clients[id]
+	if !ok {
+		return store.Client{}, errors.New("client not found")
+	}
+	return c, nil
+}
+
+// userStoreStub holds users keyed by ID.
+type userStoreStub map[int]store.User
+
+func (s userStoreStub) GetByID(ctx context.Context, id int) (store.User, error) {
+	u, ok := s[id]
+	if !ok {
+		return store.User{}, errors.New("user not found")
+	}
+	return u, nil
+}
+
+func (s userStoreStub) GetByEmail(ctx context.Context, email string) (store.User, error) {
+	return store.User{}, errors.New("user not found")
+}
+
+func (s userStoreStub) Create(ctx context.Context, u store.User) (int, error) {
+	return 0, errors.New("not implemented")
+}
+
+func (s userStoreStub) RevokeTokens(ctx context.Context, id int, at time.Time) error {
+	u, ok := s[id]
+	if !ok {
+		return errors.New("user not found")
+	}
+	u.TokensRevokedAt = at
+	s[id] = u
+	return nil
+}
+
+// revokedTokenStoreStub holds the IDs of revoked tokens.
+type revokedTokenStoreStub map[string]store.RevokedToken
+
+func (s revokedTokenStoreStub) Insert(ctx context.Context, t store.RevokedToken) error {
+	s[t.TokenID] = t
+	return nil
+}
+
+func (s revokedTokenStoreStub) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	_, ok := s[tokenID]
+	return ok, nil
+}
+
+func (s revokedTokenStoreStub) DeleteExpired(ctx context.Context, before time.Time) error {
+	return nil
+}
+
+// refreshTokenStoreStub holds refresh tokens keyed by token and records revoked families.
+// If markUsedErr is set, MarkUsed fails with it.
+type refreshTokenStoreStub struct {
+	store.RefreshTokenStore
+	tokens          map[string]store.RefreshToken
+	revokedFamilies []string
+	markUsedErr     error
+}
+
+func (s *refreshTokenStoreStub) GetByToken(ctx context.Context, token string) (store.RefreshToken, error) {
+	rt, ok := s.tokens[token]
+	if !ok {
+		return store.RefreshToken{}, errors.New("refresh token not found")
+	}
+	return rt, nil
+}
+
+func (s *refreshTokenStoreStub) Insert(ctx context.Context, rt store.RefreshToken) (int, error) {
+	rt.ID = len(s.tokens) + 1
+	s.tokens[rt.Token] = rt
+	return rt.ID, nil
+}
+
+func (s *refreshTokenStoreStub) MarkUsed(ctx context.Context, id int) error {
+	if s.markUsedErr != nil {
+		return s.markUsedErr
+	}
+
+	for k, rt := range s.tokens {
+		if rt.ID == id {
+			rt.Used = true
+			s.tokens[k] = rt
+		}
+	}
+	return nil
+}
+
+func (s *refreshTokenStoreStub) RevokeClient(ctx context.Context, userID int, clientID string) error {
+	for k, rt := range s.tokens {
+		if rt.UserID == userID && rt.ClientID == clientID {
+			rt.Revoked = true
+			s.tokens[k] = rt
+		}
+	}
+	return nil
+}
+
+func (s *refreshTokenStoreStub) RevokeFamily(ctx context.Context, familyID string) error {
+	s.revokedFamilies = append(s.revokedFamilies, familyID)
+	return nil
+}
+
+// authCodeStoreStub holds auth codes keyed by code.
+type authCodeStoreStub map[string]store.AuthCode
+
+func (s authCodeStoreStub) GetByCode(ctx context.Context, code string) (store.AuthCode, error) {
+	c, ok := s[code]
+	if !ok {
+		return store.AuthCode{}, errors.New("auth code not found")
+	}
+	return c, nil
+}
+
+func (s authCodeStoreStub) Insert(ctx context.Context, code store.AuthCode) (int, error) {
+	code.ID = len(s) + 1
+	s[code.Code] = code
+	return code.ID, nil
+}
+
+func (s authCodeStoreStub) Consume(ctx context.Context, id int, tokenFamilyID, accessTokenID string, accessTokenExpiresAt time.Time) error {
+	for k, c := range s {
+		if c.ID != id {
+			continue
+		} else if c.Consumed {
+			return store.ErrAuthCodeUsed
+		}
+
+		c.Consumed = true
+		c.TokenFamilyID = tokenFamilyID
+		c.AccessTokenID = accessTokenID
+		c.AccessTokenExpiresAt = accessTokenExpiresAt
+		s[k] = c
+	}
+	return nil
+}
+
+// hashSecret hashes a client secret using parameters cheap enough for tests.
+func hashSecret(t *testing.T, secret string) string {
+	t.Helper()
+
+	h, err := crypto.GetPasswordHash(secret, crypto.ArgonParams{Time: 1, Memory: 64, Threads: 1, KeyLen: 32, SaltLen: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestService_ValidateToken(t *testing.T) {
+	s := &Service{
+		userStore: userStoreStub{
+			1: {ID: 1},
+			2: {ID: 2, TokensRevokedAt: time.Now().Add(time.Minute)},
+		},
+		clientStore: clientStoreStub{clients: map[string]store.Client{
+			"active":   {ClientID: "active"},
+			"disabled": {ClientID: "disabled", Disabled: true},
+		}},
+		revokedTokenStore: revokedTokenStoreStub{},
+		jwtSettings:       testJWTSettings,
+	}
+
+	tests := []struct {
+		name     string
+		subject  string
+		clientID string
+		revoke   bool
+		wantErr  error
+	}{
+		{name: "Token without a client", subject: "1", clientID: "", wantErr: nil},
+		{name: "Active client", subject: "1", clientID: "active", wantErr: nil},
+		{name: "Revoked token", subject: "1", clientID: "active", revoke: true, wantErr: ErrTokenRevoked},
+		{name: "Disabled client", subject: "1", clientID: "disabled", wantErr: ErrTokenRevoked},
+		{name: "Deleted client", subject: "1", clientID: "deleted", wantErr: ErrTokenRevoked},
+		{name: "Token revoked by its user", subject: "2", clientID: "", wantErr: ErrTokenRevoked},
+		{name: "Deleted user", subject: "3", clientID: "", wantErr: ErrTokenRevoked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := generateJWT(testJWTSettings, accessTokenParams{Subject: tt.subject, ClientID: tt.clientID})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.revoke {
+				s.revokedTokenStore.Insert(context.Background(), store.RevokedToken{TokenID: token.ID})
+			}
+
+			_, err = s.ValidateToken(context.Background(), token.AccessToken)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_clientSecretMatches(t *testing.T) {
+	params := crypto.ArgonParams{Time: 1, Memory: 64, Threads: 1, KeyLen: 32, SaltLen: 16}
+	hash := func(secret string) string {
+		h, err := crypto.GetPasswordHash(secret, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	now := time.Now()
+	rotated := store.Client{
+		SecretHash:              hash("new"),
+		PreviousSecretHash:      hash("old"),
+		PreviousSecretExpiresAt: now.Add(time.Hour),
+	}
+	expired := rotated
+	expired.PreviousSecretExpiresAt = now.Add(-time.Second)
+
+	tests := []struct {
+		name   string
+		client store.Client
+		secret string
+		want   bool
+	}{
+		{
+			name:   "Current secret",
+			client: store.Client{SecretHash: hash("new")},
+			secret: "new",
+			want:   true,
+		},
+		{
+			name:   "Wrong secret",
+			client: store.Client{SecretHash: hash("new")},
+			secret: "old",
+			want:   false,
+		},
+		{
+			name:   "Current secret after rotation",
+			client: rotated,
+			secret: "new",
+			want:   true,
+		},
+		{
+			name:   "Previous secret within grace period",
+			client: rotated,
+			secret: "old",
+			want:   true,
+		},
+		{
+			name:   "Previous secret after grace period",
+			client: expired,
+			secret: "old",
+			want:   false,
+		},
+		{
+			name:   "Empty secret",
+			client: store.Client{SecretHash: hash("new")},
+			secret: "",
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clientSecretMatches(tt.client, tt.secret, now); got != tt.want {
+				t.Errorf("clientSecretMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_clientLifespans(t *testing.T) {
+	s := &Service{
+		jwtSettings:          JWTSettings{Lifespan: 3600},
+		refreshTokenSettings: RefreshTokenSettings{Lifespan: 86400},
+		authCodeSettings:     AuthCodeSettings{Lifespan: 600},
+	}
+
+	tests := []struct {
+		name             string
+		lifespans        store.TokenLifespans
+		wantAccessToken  int
+		wantIDToken      int
+		wantRefreshToken int
+		wantAuthCode     int
+	}{
+		{
+			name:             "Server defaults",
+			lifespans:        store.TokenLifespans{},
+			wantAccessToken:  3600,
+			wantIDToken:      3600,
+			wantRefreshToken: 86400,
+			wantAuthCode:     600,
+		},
+		{
+			name:             "Client overrides",
+			lifespans:        store.TokenLifespans{AccessToken: 300, RefreshToken: 900, IDToken: 120, AuthCode: 60},
+			wantAccessToken:  300,
+			wantIDToken:      120,
+			wantRefreshToken: 900,
+			wantAuthCode:     60,
+		},
+		{
+			name:             "Access token override only",
+			lifespans:        store.TokenLifespans{AccessToken: 300},
+			wantAccessToken:  300,
+			wantIDToken:      300,
+			wantRefreshToken: 86400,
+			wantAuthCode:     600,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := store.Client{TokenLifespans: tt.lifespans}
+
+			settings := s.clientJWTSettings(client)
+			if settings.Lifespan != tt.wantAccessToken {
+				t.Errorf("access token lifespan = %d, want %d", settings.Lifespan, tt.wantAccessToken)
+			}
+			if got := settings.idTokenLifespan(); got != tt.wantIDToken {
+				t.Errorf("ID token lifespan = %d, want %d", got, tt.wantIDToken)
+			}
+			if got := s.refreshTokenLifespan(client); got != tt.wantRefreshToken {
+				t.Errorf("refresh token lifespan = %d, want %d", got, tt.wantRefreshToken)
+			}
+			if got := s.authCodeLifespan(client); got != tt.wantAuthCode {
+				t.Errorf("auth code lifespan = %d, want %d", got, tt.wantAuthCode)
+			}
+		})
+	}
+
+	if s.jwtSettings.Lifespan != 3600 {
+		t.Error("clientJWTSettings() modified the server's settings")
+	}
+}
+
+func TestService_ConvertCodeToToken(t *testing.T) {
+	client := store.Client{
+		ClientID:                "client",
+		SecretHash:              hashSecret(t, "secret"),
+		TokenEndpointAuthMethod: store.ClientSecretPost,
+		GrantTypes:              []string{AuthorizationCodeGrant, RefreshTokenGrant},
+	}
+	other := store.Client{
+		ClientID:                "other",
+		SecretHash:              client.SecretHash,
+		TokenEndpointAuthMethod: store.ClientSecretPost,
+		GrantTypes:              client.GrantTypes,
+	}
+
+	code := store.AuthCode{
+		ID:          1,
+		Code:        "code",
+		UserID:      1,
+		ClientID:    "client",
+		RedirectURL: "https://example.com/cb",
+		AuthTime:    time.Now().Add(-5 * time.Minute).Truncate(time.Second),
+		CreatedAt:   time.Now(),
+	}
+	expired := code
+	expired.CreatedAt = time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name        string
+		code        store.AuthCode
+		clientID    string
+		redirectURL string
+		wantErr     bool
+	}{
+		{
+			name:        "Valid code",
+			code:        code,
+			clientID:    "client",
+			redirectURL: "https://example.com/cb",
+			wantErr:     false,
+		},
+		{
+			name:        "Code issued to another client",
+			code:        code,
+			clientID:    "other",
+			redirectURL: "https://example.com/cb",
+			wantErr:     true,
+		},
+		{
+			name:        "Redirect URL mismatch",
+			code:        code,
+			clientID:    "client",
+			redirectURL: "https://example.com/other",
+			wantErr:     true,
+		},
+		{
+			name:        "Expired code",
+			code:        expired,
+			clientID:    "client",
+			redirectURL: "https://example.com/cb",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codes := authCodeStoreStub{tt.code.Code: tt.code}
+			s := &Service{
+				clientStore:       clientStoreStub{clients: map[string]store.Client{"client": client, "other": other}},
+				authCodeStore:     codes,
+				refreshTokenStore: &refreshTokenStoreStub{tokens: map[string]store.RefreshToken{}},
+				revokedTokenStore: revokedTokenStoreStub{},
+				jwtSettings:       testJWTSettings,
+				authCodeSettings:  AuthCodeSettings{Lifespan: 600},
+			}
+
+			ca := ClientAuth{ClientID: tt.clientID, ClientSecret: "secret", Method: store.ClientSecretPost}
+			token, err := s.ConvertCodeToToken(context.Background(), tt.code.Code, ca, tt.redirectURL, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConvertCodeToToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var oauthErr *Error
+			if tt.wantErr && (!errors.As(err, &oauthErr) || oauthErr.Code != InvalidGrant) {
+				t.Errorf("ConvertCodeToToken() error = %v, want an invalid_grant error", err)
+			}
+
+			// Rejected requests must not use up the code.
+			if consumed := codes[tt.code.Code].Consumed; consumed == tt.wantErr {
+				t.Errorf("code consumed = %v, want %v", consumed, !tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if token.RefreshToken == "" {
+				t.Errorf("ConvertCodeToToken() = %+v, want a refresh token", token)
+			}
+
+			// The auth time is when the user signed in, not when the code was issued.
+			claims, err := s.ValidateToken(context.Background(), token.AccessToken)
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			} else if !claims.AuthTime.Equal(tt.code.AuthTime) {
+				t.Errorf("auth_time = %v, want %v", claims.AuthTime, tt.code.AuthTime)
+			}
+		})
+	}
+}
+
+func TestService_ConvertCodeToToken_replay(t *testing.T) {
+	client := store.Client{
+		ClientID:                "client",
+		SecretHash:              hashSecret(t, "secret"),
+		TokenEndpointAuthMethod: store.ClientSecretPost,
+		GrantTypes:              []string{AuthorizationCodeGrant, RefreshTokenGrant},
+	}
+
+	refreshTokens := &refreshTokenStoreStub{tokens: map[string]store.RefreshToken{}}
+	s := &Service{
+		clientStore: clientStoreStub{clients: map[string]store.Client{"client": client}},
+		authCodeStore: authCodeStoreStub{"code": {
+			ID:          1,
+			Code:        "code",
+			UserID:      1,
+			ClientID:    "client",
+			RedirectURL: "https://example.com/cb",
+			CreatedAt:   time.Now(),
+		}},
+		refreshTokenStore: refreshTokens,
+		revokedTokenStore: revokedTokenStoreStub{},
+		jwtSettings:       testJWTSettings,
+		authCodeSettings:  AuthCodeSettings{Lifespan: 600},
+	}
+
+	ctx := context.Background()
+	ca := ClientAuth{ClientID: "client", ClientSecret: "secret", Method: store.ClientSecretPost}
+
+	token, err := s.ConvertCodeToToken(ctx, "code", ca, "https://example.com/cb", "")
+	if err != nil {
+		t.Fatalf("ConvertCodeToToken() error = %v", err)
+	}
+
+	_, err = s.ConvertCodeToToken(ctx, "code", ca, "https://example.com/cb", "")
+	var oauthErr *Error
+	if !errors.As(err, &oauthErr) || oauthErr.Code != InvalidGrant {
+		t.Fatalf("replayed ConvertCodeToToken() error = %v, want an invalid_grant error", err)
+	}
+
+	if _, err = s.ValidateToken(ctx, token.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("ValidateToken() error = %v, want %v", err, ErrTokenRevoked)
+	}
+
+	familyID := refreshTokens.tokens[token.RefreshToken].FamilyID
+	if len(refreshTokens.revokedFamilies) != 1 || refreshTokens.revokedFamilies[0] != familyID {
+		t.Errorf("revoked families = %v, want [%s]", refreshTokens.revokedFamilies, familyID)
+	}
+}
+
+func TestService_RefreshToken(t *testing.T) {
+	client := store.Client{
+		ClientID:                "client",
+		Type:                    store.PublicClient,
+		TokenEndpointAuthMethod: store.ClientAuthNone,
+		GrantTypes:              []string{AuthorizationCodeGrant, RefreshTokenGrant},
+	}
+
+	valid := store.RefreshToken{
+		ID:        1,
+		Token:     "token",
+		FamilyID:  "family",
+		UserID:    1,
+		ClientID:  "client",
+		Scope:     "read write",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	used := valid
+	used.Used = true
+	revoked := valid
+	revoked.Revoked = true
+	expired := valid
+	expired.ExpiresAt = time.Now().Add(-time.Second)
+
+	tests := []struct {
+		name        string
+		rt          store.RefreshToken
+		clientID    string
+		scope       string
+		markUsedErr error
+		wantCode    ErrorCode
+		wantRevoked bool
+		wantScope   string
+	}{
+		{
+			name:      "Valid token",
+			rt:        valid,
+			clientID:  "client",
+			wantScope: "read write",
+		},
+		{
+			name:      "Narrowed scope",
+			rt:        valid,
+			clientID:  "client",
+			scope:     "read",
+			wantScope: "read",
+		},
+		{
+			name:     "Scope not originally granted",
+			rt:       valid,
+			clientID: "client",
+			scope:    "read admin",
+			wantCode: InvalidScope,
+		},
+		{
+			name:        "Used token revokes the family",
+			rt:          used,
+			clientID:    "client",
+			wantCode:    InvalidGrant,
+			wantRevoked: true,
+		},
+		{
+			name:     "Revoked token",
+			rt:       revoked,
+			clientID: "client",
+			wantCode: InvalidGrant,
+		},
+		{
+			name:     "Expired token",
+			rt:       expired,
+			clientID: "client",
+			wantCode: InvalidGrant,
+		},
+		{
+			name:     "Token issued to another client",
+			rt:       valid,
+			clientID: "other",
+			wantCode: InvalidGrant,
+		},
+		{
+			name:        "Token used concurrently",
+			rt:          valid,
+			clientID:    "client",
+			markUsedErr: store.ErrRefreshTokenUsed,
+			wantCode:    InvalidGrant,
+			wantRevoked: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refreshTokens := &refreshTokenStoreStub{
+				tokens:      map[string]store.RefreshToken{tt.rt.Token: tt.rt},
+				markUsedErr: tt.markUsedErr,
+			}
+			s := &Service{
+				clientStore:       clientStoreStub{clients: map[string]store.Client{"client": client}},
+				refreshTokenStore: refreshTokens,
+				jwtSettings:       testJWTSettings,
+			}
+
+			ca := ClientAuth{ClientID: tt.clientID, Method: store.ClientAuthNone}
+			token, err := s.RefreshToken(context.Background(), tt.rt.Token, ca, tt.scope)
+
+			var oauthErr *Error
+			if tt.wantCode == "" && err != nil {
+				t.Fatalf("RefreshToken() error = %v", err)
+			} else if tt.wantCode != "" && (!errors.As(err, &oauthErr) || oauthErr.Code != tt.wantCode) {
+				t.Fatalf("RefreshToken() error = %v, want %s", err, tt.wantCode)
+			}
+
+			if revoked := len(refreshTokens.revokedFamilies) > 0; revoked != tt.wantRevoked {
+				t.Errorf("family revoked = %v, want %v", revoked, tt.wantRevoked)
+			}
+
+			if tt.wantCode != "" {
+				return
+			}
+
+			if token.Scope != tt.wantScope {
+				t.Errorf("RefreshToken() scope = %q, want %q", token.Scope, tt.wantScope)
+			}
+
+			if !refreshTokens.tokens[tt.rt.Token].Used {
+				t.Error("RefreshToken() did not mark the token as used")
+			}
+
+			rotated, ok := refreshTokens.tokens[token.RefreshToken]
+			if !ok {
+				t.Fatal("RefreshToken() did not store the new refresh token")
+			}
+			if rotated.FamilyID != tt.rt.FamilyID || rotated.Scope != tt.rt.Scope {
+				t.Errorf("new refresh token = %+v, want family %s and scope %q", rotated, tt.rt.FamilyID, tt.rt.Scope)
+			}
+		})
+	}
+}
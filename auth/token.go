@@ -6,31 +6,125 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/mattmeyers/heimdall/crypto"
 )
 
 // Token holds the information required for transmitting the JWT to the client.
 type Token struct {
-	AccessToken string
-	Lifespan    int
+	AccessToken  string
+	RefreshToken string
+	IDToken      string
+	Lifespan     int
+	Scope        string
+	// ID and ExpiresAt are the access token's jti and expiration time. They are only used
+	// by the server.
+	ID        string    `json:"-"`
+	ExpiresAt time.Time `json:"-"`
+}
+
+// accessTokenType is the typ header of access tokens (RFC 9068 section 2.1).
+const accessTokenType = "at+jwt"
+
+// accessTokenClaims are the claims encoded into every access token, following the JWT
+// profile for access tokens (RFC 9068).
+type accessTokenClaims struct {
+	jwt.RegisteredClaims
+	ClientID string           `json:"client_id,omitempty"`
+	Scope    string           `json:"scope,omitempty"`
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+}
+
+// TokenClaims are the claims of a validated access token.
+type TokenClaims struct {
+	ID        string
+	Issuer    string
+	Subject   string
+	ClientID  string
+	Scopes    []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	// AuthTime is the time the user authenticated, or the zero time if there is no user.
+	AuthTime time.Time
+}
+
+func (c accessTokenClaims) toTokenClaims() TokenClaims {
+	tc := TokenClaims{
+		ID:        c.ID,
+		Issuer:    c.Issuer,
+		Subject:   c.Subject,
+		ClientID:  c.ClientID,
+		Scopes:    parseScope(c.Scope),
+		ExpiresAt: c.ExpiresAt.Time,
+	}
+
+	if c.IssuedAt != nil {
+		tc.IssuedAt = c.IssuedAt.Time
+	}
+
+	if c.AuthTime != nil {
+		tc.AuthTime = c.AuthTime.Time
+	}
+
+	return tc
+}
+
+// accessTokenParams describe who an access token is issued to.
+type accessTokenParams struct {
+	// Subject is the user ID, or the client ID when the client acts on its own behalf.
+	Subject  string
+	ClientID string
+	Scopes   []string
+	// AuthTime is the time the user last authenticated. It is omitted if zero.
+	AuthTime time.Time
 }
 
 type signingAlgorithm string
 
 // The valid JWT hashing function algorithms.
 const (
-	HMAC256Algorithm signingAlgorithm = "HS256"
+	HMAC256Algorithm  signingAlgorithm = "HS256"
+	RSA256Algorithm   signingAlgorithm = "RS256"
+	ECDSA256Algorithm signingAlgorithm = "ES256"
+	EdDSAAlgorithm    signingAlgorithm = "EdDSA"
 )
 
 func (a signingAlgorithm) isValid() bool {
-	return a == HMAC256Algorithm
+	switch a {
+	case HMAC256Algorithm, RSA256Algorithm, ECDSA256Algorithm, EdDSAAlgorithm:
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseSigningAlgorithm converts the JWT alg name into a signing algorithm.
+func ParseSigningAlgorithm(alg string) (signingAlgorithm, error) {
+	a := signingAlgorithm(alg)
+	if !a.isValid() {
+		return "", errors.New("unknown signing algorithm")
+	}
+
+	return a, nil
 }
 
 // JWTSettings are the available configuration values for generating JWTs.
 type JWTSettings struct {
-	Issuer     string
-	Lifespan   int
+	Issuer   string
+	Lifespan int
+	// IDTokenLifespan is the number of seconds an ID token is valid. Lifespan is used if it
+	// is zero.
+	IDTokenLifespan int
+	// SigningKey is the shared secret used to sign tokens with HS256.
 	SigningKey string
 	Algorithm  signingAlgorithm
+	// Keys holds the private keys used to sign tokens with an asymmetric algorithm. The
+	// active key must use Algorithm.
+	Keys *KeySet
+	// Audience, if set, must be contained in the aud claim of validated tokens.
+	Audience string
+	// ClockSkew is the number of seconds of leeway allowed when validating time based
+	// claims, to account for clock differences between servers.
+	ClockSkew int
 }
 
 func (s JWTSettings) validate() error {
@@ -42,32 +136,110 @@ func (s JWTSettings) validate() error {
 		return errors.New("JWT lifetime must be a positive integer")
 	}
 
-	if strings.TrimSpace(s.SigningKey) == "" {
-		return errors.New("JWT signing key required")
+	if s.IDTokenLifespan < 0 {
+		return errors.New("ID token lifetime cannot be negative")
 	}
 
 	if !s.Algorithm.isValid() {
 		return errors.New("unknown signing algorithm")
 	}
 
+	if s.ClockSkew < 0 {
+		return errors.New("JWT clock skew cannot be negative")
+	}
+
+	if s.Algorithm == HMAC256Algorithm {
+		if strings.TrimSpace(s.SigningKey) == "" {
+			return errors.New("JWT signing key required")
+		}
+	} else if s.Keys == nil {
+		return errors.New("JWT key set required")
+	} else if s.Keys.Active().Algorithm != s.Algorithm {
+		return errors.New("active JWT key does not match the signing algorithm")
+	}
+
 	return nil
 }
 
-func generateJWT(settings JWTSettings) (Token, error) {
+// signsIDTokens reports whether ID tokens can be issued. ID tokens are only signed with an
+// asymmetric key, since relying parties cannot verify tokens signed with the server's own
+// HS256 secret.
+func (s JWTSettings) signsIDTokens() bool {
+	return s.Algorithm != HMAC256Algorithm
+}
+
+// activeKey returns the key used to sign new tokens.
+func (s JWTSettings) activeKey() Key {
+	if s.Algorithm == HMAC256Algorithm {
+		return newHMACKey(s.SigningKey)
+	}
+
+	return s.Keys.Active()
+}
+
+// getKey returns the key with the provided ID.
+func (s JWTSettings) getKey(id string) (Key, bool) {
+	if s.Algorithm == HMAC256Algorithm {
+		k := newHMACKey(s.SigningKey)
+		return k, k.ID == id
+	}
+
+	return s.Keys.Get(id)
+}
+
+// publicKeys returns the public halves of every key that can verify tokens. Nothing is
+// returned when using HS256 since the shared secret must never be published.
+func (s JWTSettings) publicKeys() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if s.Algorithm == HMAC256Algorithm {
+		return set
+	}
+
+	for _, k := range s.Keys.All() {
+		if jwk, ok := k.publicJWK(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+
+	return set
+}
+
+// generateJWT generates a signed access token for the provided subject and client. The
+// audience is the configured audience, falling back to the client ID.
+func generateJWT(settings JWTSettings, p accessTokenParams) (Token, error) {
 	if err := settings.validate(); err != nil {
 		return Token{}, err
 	}
 
-	t := jwt.New(jwt.GetSigningMethod(string(settings.Algorithm)))
+	jti, err := generateTokenID()
+	if err != nil {
+		return Token{}, err
+	}
 
 	now := time.Now()
-	t.Claims = &jwt.RegisteredClaims{
-		Issuer:    settings.Issuer,
-		IssuedAt:  jwt.NewNumericDate(now),
-		ExpiresAt: jwt.NewNumericDate(now.Add(time.Second * time.Duration(settings.Lifespan))),
+	claims := &accessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    settings.Issuer,
+			Subject:   p.Subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Second * time.Duration(settings.Lifespan))),
+		},
+		ClientID: p.ClientID,
+		Scope:    formatScope(p.Scopes),
+	}
+
+	if settings.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{settings.Audience}
+	} else if p.ClientID != "" {
+		claims.Audience = jwt.ClaimStrings{p.ClientID}
 	}
 
-	signed, err := t.SignedString([]byte(settings.SigningKey))
+	if !p.AuthTime.IsZero() {
+		claims.AuthTime = jwt.NewNumericDate(p.AuthTime)
+	}
+
+	signed, err := signJWT(settings, accessTokenType, claims)
 	if err != nil {
 		return Token{}, err
 	}
@@ -75,14 +247,105 @@ func generateJWT(settings JWTSettings) (Token, error) {
 	return Token{
 		AccessToken: signed,
 		Lifespan:    settings.Lifespan,
+		Scope:       claims.Scope,
+		ID:          jti,
+		ExpiresAt:   claims.ExpiresAt.Time,
 	}, nil
 }
 
-func validateJWT(token string, settings JWTSettings) error {
-	_, err := jwt.Parse(
-		token,
-		func(t *jwt.Token) (interface{}, error) { return []byte(settings.SigningKey), nil },
-	)
+// signJWT signs the claims with the active key. The kid header is always set so that
+// verifiers can select the correct key.
+func signJWT(settings JWTSettings, typ string, claims jwt.Claims) (string, error) {
+	key := settings.activeKey()
+
+	t := jwt.NewWithClaims(jwt.GetSigningMethod(string(key.Algorithm)), claims)
+	t.Header["kid"] = key.ID
+	t.Header["typ"] = typ
+
+	return t.SignedString(key.signingKey())
+}
+
+func generateTokenID() (string, error) {
+	return crypto.GenerateRandHexString(16)
+}
+
+// The errors returned when a token fails validation.
+var (
+	ErrMalformedToken   = errors.New("token is malformed")
+	ErrInvalidSignature = errors.New("token signature is invalid")
+	ErrTokenExpired     = errors.New("token has expired")
+	ErrTokenNotYetValid = errors.New("token is not valid yet")
+	ErrInvalidIssuer    = errors.New("token issuer is invalid")
+	ErrInvalidAudience  = errors.New("token audience is invalid")
+	ErrInvalidTokenType = errors.New("token is not an access token")
+)
+
+// validateJWT verifies the token's signature and claims. Only tokens signed with the
+// configured algorithm by a known key are accepted, and the issuer must match. The
+// audience is checked if one is configured.
+func validateJWT(token string, settings JWTSettings) (accessTokenClaims, error) {
+	parser := &jwt.Parser{
+		ValidMethods: []string{string(settings.Algorithm)},
+		// Time based claims are validated below to allow for clock skew.
+		SkipClaimsValidation: true,
+	}
+
+	var claims accessTokenClaims
+	t, err := parser.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := settings.getKey(kid)
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+
+		if t.Method.Alg() != string(key.Algorithm) {
+			return nil, errors.New("signing method does not match key")
+		}
 
-	return err
+		return key.verificationKey(), nil
+	})
+
+	var vErr *jwt.ValidationError
+	if errors.As(err, &vErr) && vErr.Errors&jwt.ValidationErrorMalformed != 0 {
+		return accessTokenClaims{}, ErrMalformedToken
+	} else if err != nil {
+		return accessTokenClaims{}, ErrInvalidSignature
+	}
+
+	// Prevents other JWTs signed by the same keys from being used as access tokens.
+	if typ, _ := t.Header["typ"].(string); typ != accessTokenType {
+		return accessTokenClaims{}, ErrInvalidTokenType
+	}
+
+	if err := validateClaims(claims.RegisteredClaims, settings, time.Now()); err != nil {
+		return accessTokenClaims{}, err
+	}
+
+	return claims, nil
+}
+
+func validateClaims(claims jwt.RegisteredClaims, settings JWTSettings, now time.Time) error {
+	skew := time.Second * time.Duration(settings.ClockSkew)
+
+	if claims.ExpiresAt == nil || now.After(claims.ExpiresAt.Add(skew)) {
+		return ErrTokenExpired
+	}
+
+	if claims.NotBefore != nil && now.Add(skew).Before(claims.NotBefore.Time) {
+		return ErrTokenNotYetValid
+	}
+
+	if claims.IssuedAt != nil && now.Add(skew).Before(claims.IssuedAt.Time) {
+		return ErrTokenNotYetValid
+	}
+
+	if claims.Issuer != settings.Issuer {
+		return ErrInvalidIssuer
+	}
+
+	if settings.Audience != "" && !claims.VerifyAudience(settings.Audience, true) {
+		return ErrInvalidAudience
+	}
+
+	return nil
 }
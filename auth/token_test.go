@@ -1,7 +1,12 @@
 package auth
 
 import (
+	"crypto/x509"
+	"errors"
 	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
 )
 
 func TestJWTSettings_validate(t *testing.T) {
@@ -107,7 +112,7 @@ func Test_generateJWT(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := generateJWT(tt.settings)
+			got, err := generateJWT(tt.settings, accessTokenParams{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("generateJWT() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -119,3 +124,158 @@ func Test_generateJWT(t *testing.T) {
 		})
 	}
 }
+
+func Test_validateJWT(t *testing.T) {
+	key, err := GenerateKey(ECDSA256Algorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings := JWTSettings{
+		Issuer:    "Heimdall",
+		Lifespan:  60,
+		Algorithm: ECDSA256Algorithm,
+		Keys:      NewKeySet(key),
+		Audience:  "api",
+		ClockSkew: 30,
+	}
+
+	now := time.Now()
+	validClaims := func() jwt.RegisteredClaims {
+		return jwt.RegisteredClaims{
+			Issuer:    "Heimdall",
+			Audience:  jwt.ClaimStrings{"api"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		}
+	}
+
+	sign := func(method jwt.SigningMethod, kid string, signingKey interface{}, claims jwt.RegisteredClaims) string {
+		tok := jwt.NewWithClaims(method, claims)
+		tok.Header["kid"] = kid
+		tok.Header["typ"] = accessTokenType
+		s, err := tok.SignedString(signingKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	// The public key is the only "secret" an attacker attempting algorithm confusion knows.
+	publicDER, err := x509.MarshalPKIXPublicKey(key.verificationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr error
+	}{
+		{
+			name: "Valid",
+			token: func() string {
+				return sign(jwt.SigningMethodES256, key.ID, key.signingKey(), validClaims())
+			},
+		},
+		{
+			name: "Expired within clock skew",
+			token: func() string {
+				c := validClaims()
+				c.ExpiresAt = jwt.NewNumericDate(now.Add(-10 * time.Second))
+				return sign(jwt.SigningMethodES256, key.ID, key.signingKey(), c)
+			},
+		},
+		{
+			name: "Expired",
+			token: func() string {
+				c := validClaims()
+				c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute))
+				return sign(jwt.SigningMethodES256, key.ID, key.signingKey(), c)
+			},
+			wantErr: ErrTokenExpired,
+		},
+		{
+			name: "Missing expiry",
+			token: func() string {
+				c := validClaims()
+				c.ExpiresAt = nil
+				return sign(jwt.SigningMethodES256, key.ID, key.signingKey(), c)
+			},
+			wantErr: ErrTokenExpired,
+		},
+		{
+			name: "Not yet valid",
+			token: func() string {
+				c := validClaims()
+				c.NotBefore = jwt.NewNumericDate(now.Add(time.Minute))
+				return sign(jwt.SigningMethodES256, key.ID, key.signingKey(), c)
+			},
+			wantErr: ErrTokenNotYetValid,
+		},
+		{
+			name: "Wrong issuer",
+			token: func() string {
+				c := validClaims()
+				c.Issuer = "Loki"
+				return sign(jwt.SigningMethodES256, key.ID, key.signingKey(), c)
+			},
+			wantErr: ErrInvalidIssuer,
+		},
+		{
+			name: "Wrong audience",
+			token: func() string {
+				c := validClaims()
+				c.Audience = jwt.ClaimStrings{"other"}
+				return sign(jwt.SigningMethodES256, key.ID, key.signingKey(), c)
+			},
+			wantErr: ErrInvalidAudience,
+		},
+		{
+			name: "Algorithm confusion with HS256",
+			token: func() string {
+				return sign(jwt.SigningMethodHS256, key.ID, publicDER, validClaims())
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "Unsigned",
+			token: func() string {
+				return sign(jwt.SigningMethodNone, key.ID, jwt.UnsafeAllowNoneSignatureType, validClaims())
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "Unknown key",
+			token: func() string {
+				return sign(jwt.SigningMethodES256, "unknown", key.signingKey(), validClaims())
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "Not an access token",
+			token: func() string {
+				tok := jwt.NewWithClaims(jwt.SigningMethodES256, validClaims())
+				tok.Header["kid"] = key.ID
+				s, err := tok.SignedString(key.signingKey())
+				if err != nil {
+					t.Fatal(err)
+				}
+				return s
+			},
+			wantErr: ErrInvalidTokenType,
+		},
+		{
+			name:    "Malformed",
+			token:   func() string { return "not.a.token" },
+			wantErr: ErrMalformedToken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := validateJWT(tt.token(), settings); !errors.Is(err, tt.wantErr) {
+				t.Errorf("validateJWT() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// ErrInsufficientScope is returned when a valid access token was not granted the scope
+// required to access a resource.
+var ErrInsufficientScope = errors.New("token lacks the required scope")
+
+// UserInfo holds the claims about a user returned by the OpenID Connect UserInfo endpoint.
+type UserInfo struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	Name          string `json:"name,omitempty"`
+}
+
+// UserInfo returns the claims about the user the access token was issued for. Only the
+// claims covered by the token's scopes are populated.
+func (s *Service) UserInfo(ctx context.Context, token string) (UserInfo, error) {
+	claims, err := s.ValidateToken(ctx, token)
+	if err != nil {
+		return UserInfo{}, err
+	}
+
+	if !containsScope(claims.Scopes, OpenIDScope) {
+		return UserInfo{}, ErrInsufficientScope
+	}
+
+	// Tokens issued to a client acting on its own behalf have no user.
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return UserInfo{}, ErrInsufficientScope
+	}
+
+	u, err := s.userStore.GetByID(ctx, id)
+	if err != nil {
+		return UserInfo{}, err
+	}
+
+	info := UserInfo{Subject: claims.Subject}
+
+	if containsScope(claims.Scopes, EmailScope) {
+		info.Email = u.Email
+		info.EmailVerified = &u.EmailVerified
+	}
+
+	if containsScope(claims.Scopes, ProfileScope) {
+		info.Name = u.Name
+	}
+
+	return info, nil
+}
@@ -3,47 +3,451 @@ package client
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
+	"github.com/mattmeyers/heimdall/auth"
+	"github.com/mattmeyers/heimdall/crypto"
 	"github.com/mattmeyers/heimdall/store"
 )
 
 type Service struct {
 	clientStore store.ClientStore
+	scopeStore  store.ScopeStore
+	// secretGracePeriod is how long a client's previous secret remains valid after rotation.
+	secretGracePeriod time.Duration
+	// maxLifespans are the longest lifespans, in seconds, a client can be configured with.
+	maxLifespans store.TokenLifespans
 }
 
-func NewService(s store.ClientStore) (*Service, error) {
-	return &Service{clientStore: s}, nil
+// NewService creates a client service. Every maximum lifespan must be positive.
+func NewService(
+	s store.ClientStore,
+	scopeStore store.ScopeStore,
+	secretGracePeriod time.Duration,
+	maxLifespans store.TokenLifespans,
+) (*Service, error) {
+	if secretGracePeriod < 0 {
+		return nil, errors.New("secret grace period must not be negative")
+	}
+
+	l := maxLifespans
+	if l.AccessToken <= 0 || l.RefreshToken <= 0 || l.IDToken <= 0 || l.AuthCode <= 0 {
+		return nil, errors.New("maximum token lifespans must be positive")
+	}
+
+	return &Service{
+		clientStore:       s,
+		scopeStore:        scopeStore,
+		secretGracePeriod: secretGracePeriod,
+		maxLifespans:      maxLifespans,
+	}, nil
 }
 
 func (s *Service) Get(ctx context.Context, clientID string) (store.Client, error) {
 	return s.clientStore.GetByClientID(ctx, clientID)
 }
 
-func (s *Service) Register(ctx context.Context, redirectURLs []string) (store.Client, error) {
-	err := validateRedirectURLs(redirectURLs)
+// Pagination limits used by List.
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// List returns a page of clients along with the total number of clients. A limit of zero
+// uses DefaultPageSize.
+func (s *Service) List(ctx context.Context, offset, limit int) ([]store.Client, int, error) {
+	if offset < 0 {
+		return nil, 0, errors.New("offset must not be negative")
+	}
+
+	switch {
+	case limit == 0:
+		limit = DefaultPageSize
+	case limit < 0 || limit > MaxPageSize:
+		return nil, 0, fmt.Errorf("limit must be between 1 and %d", MaxPageSize)
+	}
+
+	clients, total, err := s.clientStore.List(ctx, offset, limit)
 	if err != nil {
-		return store.Client{}, err
+		return nil, 0, err
 	}
 
-	c := store.Client{RedirectURLs: redirectURLs}
+	if clients == nil {
+		clients = []store.Client{}
+	}
+
+	return clients, total, nil
+}
+
+// Register creates a new client from the provided settings. The client's ID and secret are
+// generated, and the client authenticates with HTTP Basic unless another method is set.
+// Only a hash of the secret is stored, so the returned plaintext secret cannot be retrieved
+// again. Clients are confidential unless registered as public clients, which are not issued
+// a secret and always require PKCE. Unless other grant types are set, clients may use the
+// authorization code flow and refresh tokens.
+func (s *Service) Register(ctx context.Context, c store.Client) (store.Client, string, error) {
+	err := validateRedirectURLs(c.RedirectURLs)
+	if err != nil {
+		return store.Client{}, "", err
+	}
+
+	if err = s.validateScopes(ctx, c.AllowedScopes); err != nil {
+		return store.Client{}, "", err
+	}
+
+	if c.Type == "" {
+		c.Type = store.ConfidentialClient
+	}
+
+	if c.TokenEndpointAuthMethod == "" {
+		c.TokenEndpointAuthMethod = defaultAuthMethod(c.Type)
+	}
+
+	if err = validateAuthMethod(c.Type, c.TokenEndpointAuthMethod); err != nil {
+		return store.Client{}, "", err
+	}
+
+	if c.GrantTypes == nil {
+		c.GrantTypes = []string{auth.AuthorizationCodeGrant, auth.RefreshTokenGrant}
+	}
+
+	if c.ResponseTypes == nil && containsString(c.GrantTypes, auth.AuthorizationCodeGrant) {
+		c.ResponseTypes = []string{auth.CodeResponseType}
+	}
+
+	if err = validateGrantTypes(c.Type, c.GrantTypes, c.ResponseTypes); err != nil {
+		return store.Client{}, "", err
+	}
+
+	if err = s.validateTokenLifespans(c.TokenLifespans); err != nil {
+		return store.Client{}, "", err
+	}
 
 	if c.ClientID, err = generateClientID(); err != nil {
-		return store.Client{}, err
+		return store.Client{}, "", err
 	}
 
-	if c.ClientSecret, err = generateClientSecret(); err != nil {
-		return store.Client{}, err
+	if c.Type == store.PublicClient {
+		c.RequirePKCE = true
+		if c.ID, err = s.clientStore.Create(ctx, c); err != nil {
+			return store.Client{}, "", err
+		}
+		return c, "", nil
+	}
+
+	secret, err := generateClientSecret()
+	if err != nil {
+		return store.Client{}, "", err
+	}
+
+	if c.SecretHash, err = crypto.GetPasswordHash(secret, crypto.DefaultParams); err != nil {
+		return store.Client{}, "", err
 	}
 
 	if c.ID, err = s.clientStore.Create(ctx, c); err != nil {
+		return store.Client{}, "", err
+	}
+
+	return c, secret, nil
+}
+
+// DefaultScopes are the only scopes a client can be allowed at open registration. Other
+// scopes can only be allowed by an administrator.
+var DefaultScopes = []string{auth.OpenIDScope, auth.EmailScope, auth.ProfileScope}
+
+// RegisterOpen registers a client on behalf of an unauthenticated caller. The client is
+// registered as with Register, but it may only be allowed the default scopes and cannot use
+// the client_credentials grant, since tokens from that grant act on no user's behalf. Nor can
+// it be a resource server, which may introspect other clients' tokens.
+func (s *Service) RegisterOpen(ctx context.Context, c store.Client) (store.Client, string, error) {
+	for _, sc := range c.AllowedScopes {
+		if !containsString(DefaultScopes, sc) {
+			return store.Client{}, "", errors.New("scope can only be allowed by an administrator: " + sc)
+		}
+	}
+
+	if containsString(c.GrantTypes, auth.ClientCredentialsGrant) {
+		return store.Client{}, "", errors.New("the client_credentials grant type can only be allowed by an administrator")
+	}
+
+	if c.ResourceServer {
+		return store.Client{}, "", errors.New("resource servers can only be registered by an administrator")
+	}
+
+	return s.Register(ctx, c)
+}
+
+// HashPlaintextSecrets hashes the secrets of clients registered before secrets were hashed.
+// It returns the number of secrets that were hashed.
+func (s *Service) HashPlaintextSecrets(ctx context.Context) (int, error) {
+	hashes, err := s.clientStore.ListSecretHashes(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for clientID, secret := range hashes {
+		// Public clients have no secret to hash.
+		if secret == "" || crypto.IsPasswordHash(secret) {
+			continue
+		}
+
+		hash, err := crypto.GetPasswordHash(secret, crypto.DefaultParams)
+		if err != nil {
+			return n, err
+		}
+
+		if err = s.clientStore.UpdateSecretHash(ctx, clientID, hash); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// ClientUpdate holds changes to a client's settings. Nil fields are left unchanged.
+type ClientUpdate struct {
+	Name                    *string
+	RedirectURLs            *[]string
+	RequirePKCE             *bool
+	ResourceServer          *bool
+	AllowedScopes           *[]string
+	TokenEndpointAuthMethod *store.ClientAuthMethod
+	GrantTypes              *[]string
+	ResponseTypes           *[]string
+	TokenLifespans          *store.TokenLifespans
+}
+
+// Update applies the changes to the client and returns the updated client. Changes are
+// validated in the same way as at registration.
+func (s *Service) Update(ctx context.Context, clientID string, u ClientUpdate) (store.Client, error) {
+	c, err := s.clientStore.GetByClientID(ctx, clientID)
+	if err != nil {
+		return store.Client{}, err
+	}
+
+	if u.Name != nil {
+		c.Name = *u.Name
+	}
+
+	if u.RedirectURLs != nil {
+		if err = validateRedirectURLs(*u.RedirectURLs); err != nil {
+			return store.Client{}, err
+		}
+		c.RedirectURLs = *u.RedirectURLs
+	}
+
+	if u.RequirePKCE != nil {
+		if c.Type == store.PublicClient && !*u.RequirePKCE {
+			return store.Client{}, errors.New("public clients must use PKCE")
+		}
+		c.RequirePKCE = *u.RequirePKCE
+	}
+
+	if u.ResourceServer != nil {
+		c.ResourceServer = *u.ResourceServer
+	}
+
+	if u.AllowedScopes != nil {
+		if err = s.validateScopes(ctx, *u.AllowedScopes); err != nil {
+			return store.Client{}, err
+		}
+		c.AllowedScopes = *u.AllowedScopes
+	}
+
+	if u.TokenEndpointAuthMethod != nil {
+		if err = validateAuthMethod(c.Type, *u.TokenEndpointAuthMethod); err != nil {
+			return store.Client{}, err
+		}
+		c.TokenEndpointAuthMethod = *u.TokenEndpointAuthMethod
+	}
+
+	if u.GrantTypes != nil || u.ResponseTypes != nil {
+		if u.GrantTypes != nil {
+			c.GrantTypes = *u.GrantTypes
+		}
+		if u.ResponseTypes != nil {
+			c.ResponseTypes = *u.ResponseTypes
+		}
+
+		if err = validateGrantTypes(c.Type, c.GrantTypes, c.ResponseTypes); err != nil {
+			return store.Client{}, err
+		}
+	}
+
+	if u.TokenLifespans != nil {
+		if err = s.validateTokenLifespans(*u.TokenLifespans); err != nil {
+			return store.Client{}, err
+		}
+		c.TokenLifespans = *u.TokenLifespans
+	}
+
+	if err = s.clientStore.Update(ctx, c); err != nil {
 		return store.Client{}, err
 	}
 
 	return c, nil
 }
 
+// Disable prevents the client from starting new authorizations or obtaining tokens. Refresh
+// tokens cannot be used while the client is disabled, and its access tokens are rejected by
+// the server. Resource servers that verify access tokens themselves rather than introspecting
+// them will accept them until they expire.
+func (s *Service) Disable(ctx context.Context, clientID string) error {
+	return s.clientStore.SetDisabled(ctx, clientID, true)
+}
+
+// Enable reverses Disable.
+func (s *Service) Enable(ctx context.Context, clientID string) error {
+	return s.clientStore.SetDisabled(ctx, clientID, false)
+}
+
+// Delete permanently removes the client along with its redirect URLs, auth codes, refresh
+// tokens and consents. As with Disable, access tokens issued to the client are rejected by
+// the server but remain valid for resource servers that verify them without introspection.
+func (s *Service) Delete(ctx context.Context, clientID string) error {
+	return s.clientStore.Delete(ctx, clientID)
+}
+
+// RotateSecret generates a new secret for the client. The previous secret remains valid for
+// the configured grace period, after which only the new secret is accepted. Rotating again
+// during the grace period immediately invalidates the oldest secret. The new plaintext
+// secret is returned along with the time the previous secret expires.
+func (s *Service) RotateSecret(ctx context.Context, clientID string) (string, time.Time, error) {
+	c, err := s.clientStore.GetByClientID(ctx, clientID)
+	if err != nil {
+		return "", time.Time{}, err
+	} else if c.Type == store.PublicClient {
+		return "", time.Time{}, errors.New("public clients do not have a secret")
+	}
+
+	secret, err := generateClientSecret()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	hash, err := crypto.GetPasswordHash(secret, crypto.DefaultParams)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(s.secretGracePeriod)
+	if err = s.clientStore.RotateSecret(ctx, clientID, hash, expiresAt); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return secret, expiresAt, nil
+}
+
+// ExpirePreviousSecret ends the grace period of the client's previous secret early.
+func (s *Service) ExpirePreviousSecret(ctx context.Context, clientID string) error {
+	return s.clientStore.ExpirePreviousSecret(ctx, clientID)
+}
+
+func defaultAuthMethod(t store.ClientType) store.ClientAuthMethod {
+	if t == store.PublicClient {
+		return store.ClientAuthNone
+	}
+
+	return store.ClientSecretBasic
+}
+
+// validateAuthMethod ensures the auth method can be used by the type of client. Public
+// clients have no secret, so they cannot use a secret based method, and confidential clients
+// must always authenticate.
+func validateAuthMethod(t store.ClientType, m store.ClientAuthMethod) error {
+	switch t {
+	case store.PublicClient:
+		if m != store.ClientAuthNone {
+			return errors.New("public clients must use the none token endpoint auth method")
+		}
+	case store.ConfidentialClient:
+		if m != store.ClientSecretBasic && m != store.ClientSecretPost {
+			return errors.New("unsupported token endpoint auth method")
+		}
+	default:
+		return errors.New("unsupported client type")
+	}
+
+	return nil
+}
+
+// validateGrantTypes ensures the grant and response types are supported and consistent with
+// each other (RFC 7591 section 2.1). Refresh tokens are only issued by the authorization code
+// flow, and public clients cannot use client_credentials since they cannot authenticate.
+func validateGrantTypes(t store.ClientType, grantTypes, responseTypes []string) error {
+	if len(grantTypes) == 0 {
+		return errors.New("at least one grant type is required")
+	}
+
+	for _, g := range grantTypes {
+		switch g {
+		case auth.AuthorizationCodeGrant, auth.RefreshTokenGrant:
+		case auth.ClientCredentialsGrant:
+			if t == store.PublicClient {
+				return errors.New("public clients cannot use the client_credentials grant")
+			}
+		default:
+			return errors.New("unsupported grant type: " + g)
+		}
+	}
+
+	for _, r := range responseTypes {
+		if r != auth.CodeResponseType {
+			return errors.New("unsupported response type: " + r)
+		}
+	}
+
+	usesCodeGrant := containsString(grantTypes, auth.AuthorizationCodeGrant)
+	if usesCodeGrant != containsString(responseTypes, auth.CodeResponseType) {
+		return errors.New("the authorization_code grant type and code response type must be used together")
+	}
+
+	if containsString(grantTypes, auth.RefreshTokenGrant) && !usesCodeGrant {
+		return errors.New("the refresh_token grant type requires the authorization_code grant type")
+	}
+
+	return nil
+}
+
+// validateTokenLifespans ensures that no lifespan is negative or longer than the server's
+// maximum. The maximums keep lifespans from overflowing when converted to durations.
+func (s *Service) validateTokenLifespans(l store.TokenLifespans) error {
+	if l.AccessToken < 0 || l.RefreshToken < 0 || l.IDToken < 0 || l.AuthCode < 0 {
+		return errors.New("token lifespans cannot be negative")
+	}
+
+	for _, lifespan := range []struct {
+		name       string
+		value, max int
+	}{
+		{"access token", l.AccessToken, s.maxLifespans.AccessToken},
+		{"refresh token", l.RefreshToken, s.maxLifespans.RefreshToken},
+		{"ID token", l.IDToken, s.maxLifespans.IDToken},
+		{"auth code", l.AuthCode, s.maxLifespans.AuthCode},
+	} {
+		if lifespan.value > lifespan.max {
+			return fmt.Errorf("%s lifespan cannot exceed %d seconds", lifespan.name, lifespan.max)
+		}
+	}
+
+	return nil
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+
+	return false
+}
+
 func validateRedirectURLs(urls []string) error {
 	for _, u := range urls {
 		parsedU, err := url.Parse(u)
@@ -58,3 +462,24 @@ func validateRedirectURLs(urls []string) error {
 
 	return nil
 }
+
+// validateScopes ensures that every scope has been registered.
+func (s *Service) validateScopes(ctx context.Context, scopes []string) error {
+	registered, err := s.scopeStore.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	isRegistered := make(map[string]bool, len(registered))
+	for _, sc := range registered {
+		isRegistered[sc.Name] = true
+	}
+
+	for _, sc := range scopes {
+		if !isRegistered[sc] {
+			return errors.New("unknown scope: " + sc)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,513 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mattmeyers/heimdall/store"
+)
+
+// clientStoreStub holds a single client and records the client passed to Update.
+type clientStoreStub struct {
+	store.ClientStore
+	client  store.Client
+	updated *store.Client
+}
+
+func (s *clientStoreStub) GetByClientID(ctx context.Context, id string) (store.Client, error) {
+	if id != s.client.ClientID {
+		return store.Client{}, errors.New("client not found")
+	}
+	return s.client, nil
+}
+
+func (s *clientStoreStub) List(ctx context.Context, offset, limit int) ([]store.Client, int, error) {
+	return nil, 0, nil
+}
+
+func (s *clientStoreStub) Create(ctx context.Context, c store.Client) (int, error) {
+	return 1, nil
+}
+
+func (s *clientStoreStub) Update(ctx context.Context, c store.Client) error {
+	s.updated = &c
+	return nil
+}
+
+var testMaxLifespans = store.TokenLifespans{AccessToken: 3600, RefreshToken: 86400, IDToken: 3600, AuthCode: 600}
+
+type scopeStoreStub []store.Scope
+
+func (s scopeStoreStub) List(ctx context.Context) ([]store.Scope, error) { return s, nil }
+
+func (s scopeStoreStub) Create(ctx context.Context, sc store.Scope) (int, error) { return 0, nil }
+
+func TestNewService(t *testing.T) {
+	tests := []struct {
+		name         string
+		gracePeriod  time.Duration
+		maxLifespans store.TokenLifespans
+		wantErr      bool
+	}{
+		{name: "Valid", gracePeriod: time.Hour, maxLifespans: testMaxLifespans, wantErr: false},
+		{name: "Negative grace period", gracePeriod: -time.Hour, maxLifespans: testMaxLifespans, wantErr: true},
+		{
+			name:         "Missing maximum",
+			gracePeriod:  time.Hour,
+			maxLifespans: store.TokenLifespans{AccessToken: 3600, RefreshToken: 86400, IDToken: 3600},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewService(&clientStoreStub{}, scopeStoreStub{}, tt.gracePeriod, tt.maxLifespans)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewService() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestService_List(t *testing.T) {
+	tests := []struct {
+		name    string
+		offset  int
+		limit   int
+		wantErr bool
+	}{
+		{name: "Default limit", offset: 0, limit: 0, wantErr: false},
+		{name: "Max limit", offset: 40, limit: MaxPageSize, wantErr: false},
+		{name: "Limit too large", offset: 0, limit: MaxPageSize + 1, wantErr: true},
+		{name: "Negative limit", offset: 0, limit: -1, wantErr: true},
+		{name: "Negative offset", offset: -1, limit: 10, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{clientStore: &clientStoreStub{}}
+			clients, _, err := s.List(context.Background(), tt.offset, tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("List() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && clients == nil {
+				t.Error("List() returned nil clients, want an empty slice")
+			}
+		})
+	}
+}
+
+func TestService_Update(t *testing.T) {
+	existing := store.Client{
+		ClientID:                "abc",
+		Name:                    "Old",
+		Type:                    store.ConfidentialClient,
+		RedirectURLs:            []string{"https://example.com/cb"},
+		AllowedScopes:           []string{"read"},
+		GrantTypes:              []string{"authorization_code"},
+		ResponseTypes:           []string{"code"},
+		TokenEndpointAuthMethod: store.ClientSecretBasic,
+	}
+
+	name := "New"
+	urls := []string{"https://example.com/other"}
+	badURLs := []string{"https://example.com/cb#fragment"}
+	scopes := []string{"read", "write"}
+	unknownScopes := []string{"admin"}
+	badMethod := store.ClientAuthMethod("private_key_jwt")
+	noneMethod := store.ClientAuthNone
+	grantTypes := []string{"client_credentials"}
+	noResponseTypes := []string{}
+	maxLifespans := testMaxLifespans
+	longAccessToken := store.TokenLifespans{AccessToken: testMaxLifespans.AccessToken + 1}
+
+	tests := []struct {
+		name     string
+		clientID string
+		update   ClientUpdate
+		want     store.Client
+		wantErr  bool
+	}{
+		{
+			name:     "Empty update",
+			clientID: "abc",
+			update:   ClientUpdate{},
+			want:     existing,
+		},
+		{
+			name:     "Name and redirect URLs",
+			clientID: "abc",
+			update:   ClientUpdate{Name: &name, RedirectURLs: &urls},
+			want: store.Client{
+				ClientID:                "abc",
+				Name:                    "New",
+				Type:                    store.ConfidentialClient,
+				RedirectURLs:            urls,
+				AllowedScopes:           []string{"read"},
+				GrantTypes:              []string{"authorization_code"},
+				ResponseTypes:           []string{"code"},
+				TokenEndpointAuthMethod: store.ClientSecretBasic,
+			},
+		},
+		{
+			name:     "Allowed scopes",
+			clientID: "abc",
+			update:   ClientUpdate{AllowedScopes: &scopes},
+			want: store.Client{
+				ClientID:                "abc",
+				Name:                    "Old",
+				Type:                    store.ConfidentialClient,
+				RedirectURLs:            []string{"https://example.com/cb"},
+				AllowedScopes:           scopes,
+				GrantTypes:              []string{"authorization_code"},
+				ResponseTypes:           []string{"code"},
+				TokenEndpointAuthMethod: store.ClientSecretBasic,
+			},
+		},
+		{
+			name:     "Unknown client",
+			clientID: "xyz",
+			update:   ClientUpdate{Name: &name},
+			wantErr:  true,
+		},
+		{
+			name:     "Redirect URL with fragment",
+			clientID: "abc",
+			update:   ClientUpdate{RedirectURLs: &badURLs},
+			wantErr:  true,
+		},
+		{
+			name:     "Unregistered scope",
+			clientID: "abc",
+			update:   ClientUpdate{AllowedScopes: &unknownScopes},
+			wantErr:  true,
+		},
+		{
+			name:     "Unsupported auth method",
+			clientID: "abc",
+			update:   ClientUpdate{TokenEndpointAuthMethod: &badMethod},
+			wantErr:  true,
+		},
+		{
+			name:     "Confidential client without authentication",
+			clientID: "abc",
+			update:   ClientUpdate{TokenEndpointAuthMethod: &noneMethod},
+			wantErr:  true,
+		},
+		{
+			name:     "Grant types",
+			clientID: "abc",
+			update:   ClientUpdate{GrantTypes: &grantTypes, ResponseTypes: &noResponseTypes},
+			want: store.Client{
+				ClientID:                "abc",
+				Name:                    "Old",
+				Type:                    store.ConfidentialClient,
+				RedirectURLs:            []string{"https://example.com/cb"},
+				AllowedScopes:           []string{"read"},
+				GrantTypes:              grantTypes,
+				ResponseTypes:           noResponseTypes,
+				TokenEndpointAuthMethod: store.ClientSecretBasic,
+			},
+		},
+		{
+			name:     "Maximum token lifespans",
+			clientID: "abc",
+			update:   ClientUpdate{TokenLifespans: &maxLifespans},
+			want: store.Client{
+				ClientID:                "abc",
+				Name:                    "Old",
+				Type:                    store.ConfidentialClient,
+				RedirectURLs:            []string{"https://example.com/cb"},
+				AllowedScopes:           []string{"read"},
+				GrantTypes:              []string{"authorization_code"},
+				ResponseTypes:           []string{"code"},
+				TokenEndpointAuthMethod: store.ClientSecretBasic,
+				TokenLifespans:          testMaxLifespans,
+			},
+		},
+		{
+			name:     "Token lifespan above maximum",
+			clientID: "abc",
+			update:   ClientUpdate{TokenLifespans: &longAccessToken},
+			wantErr:  true,
+		},
+		{
+			name:     "Negative token lifespan",
+			clientID: "abc",
+			update:   ClientUpdate{TokenLifespans: &store.TokenLifespans{AccessToken: -1}},
+			wantErr:  true,
+		},
+		{
+			name:     "Grant types without matching response types",
+			clientID: "abc",
+			update:   ClientUpdate{GrantTypes: &grantTypes},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := &clientStoreStub{client: existing}
+			s := &Service{
+				clientStore:  cs,
+				scopeStore:   scopeStoreStub{{Name: "read"}, {Name: "write"}},
+				maxLifespans: testMaxLifespans,
+			}
+
+			got, err := s.Update(context.Background(), tt.clientID, tt.update)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Update() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if cs.updated != nil {
+					t.Error("Update() stored an invalid client")
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Update() = %+v, want %+v", got, tt.want)
+			}
+			if cs.updated == nil || !reflect.DeepEqual(*cs.updated, tt.want) {
+				t.Errorf("Update() stored %+v, want %+v", cs.updated, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_Update_publicClient(t *testing.T) {
+	existing := store.Client{
+		ClientID:                "abc",
+		Type:                    store.PublicClient,
+		RequirePKCE:             true,
+		TokenEndpointAuthMethod: store.ClientAuthNone,
+	}
+
+	noPKCE := false
+	basic := store.ClientSecretBasic
+
+	tests := []struct {
+		name   string
+		update ClientUpdate
+	}{
+		{name: "Disable PKCE", update: ClientUpdate{RequirePKCE: &noPKCE}},
+		{name: "Secret based auth method", update: ClientUpdate{TokenEndpointAuthMethod: &basic}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{clientStore: &clientStoreStub{client: existing}}
+			if _, err := s.Update(context.Background(), "abc", tt.update); err == nil {
+				t.Error("Update() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestService_Register(t *testing.T) {
+	tests := []struct {
+		name       string
+		client     store.Client
+		wantType   store.ClientType
+		wantMethod store.ClientAuthMethod
+		wantPKCE   bool
+		wantSecret bool
+		wantErr    bool
+	}{
+		{
+			name:       "Defaults to confidential",
+			client:     store.Client{},
+			wantType:   store.ConfidentialClient,
+			wantMethod: store.ClientSecretBasic,
+			wantSecret: true,
+		},
+		{
+			name:       "Confidential with post",
+			client:     store.Client{TokenEndpointAuthMethod: store.ClientSecretPost},
+			wantType:   store.ConfidentialClient,
+			wantMethod: store.ClientSecretPost,
+			wantSecret: true,
+		},
+		{
+			name:       "Public",
+			client:     store.Client{Type: store.PublicClient},
+			wantType:   store.PublicClient,
+			wantMethod: store.ClientAuthNone,
+			wantPKCE:   true,
+			wantSecret: false,
+		},
+		{
+			name:    "Public with secret auth method",
+			client:  store.Client{Type: store.PublicClient, TokenEndpointAuthMethod: store.ClientSecretBasic},
+			wantErr: true,
+		},
+		{
+			name:    "Confidential without authentication",
+			client:  store.Client{TokenEndpointAuthMethod: store.ClientAuthNone},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown type",
+			client:  store.Client{Type: "trusted"},
+			wantErr: true,
+		},
+		{
+			name:       "Maximum token lifespans",
+			client:     store.Client{TokenLifespans: testMaxLifespans},
+			wantType:   store.ConfidentialClient,
+			wantMethod: store.ClientSecretBasic,
+			wantSecret: true,
+		},
+		{
+			name:    "Auth code lifespan above maximum",
+			client:  store.Client{TokenLifespans: store.TokenLifespans{AuthCode: testMaxLifespans.AuthCode + 1}},
+			wantErr: true,
+		},
+		{
+			name:    "Lifespan that would overflow",
+			client:  store.Client{TokenLifespans: store.TokenLifespans{RefreshToken: 1 << 40}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{clientStore: &clientStoreStub{}, scopeStore: scopeStoreStub{}, maxLifespans: testMaxLifespans}
+			got, secret, err := s.Register(context.Background(), tt.client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Register() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got.Type != tt.wantType {
+				t.Errorf("Register() type = %v, want %v", got.Type, tt.wantType)
+			}
+			if got.TokenEndpointAuthMethod != tt.wantMethod {
+				t.Errorf("Register() auth method = %v, want %v", got.TokenEndpointAuthMethod, tt.wantMethod)
+			}
+			if got.RequirePKCE != tt.wantPKCE {
+				t.Errorf("Register() require PKCE = %v, want %v", got.RequirePKCE, tt.wantPKCE)
+			}
+			if (secret != "") != tt.wantSecret || (got.SecretHash != "") != tt.wantSecret {
+				t.Errorf("Register() issued secret = %v, want %v", secret != "", tt.wantSecret)
+			}
+		})
+	}
+}
+
+func TestService_RegisterOpen(t *testing.T) {
+	scopes := scopeStoreStub{{Name: "openid"}, {Name: "email"}, {Name: "admin"}}
+
+	tests := []struct {
+		name           string
+		scopes         []string
+		grantTypes     []string
+		resourceServer bool
+		wantErr        bool
+	}{
+		{name: "No scopes", scopes: nil, wantErr: false},
+		{name: "Default scopes", scopes: []string{"openid", "email"}, wantErr: false},
+		{name: "Privileged scope", scopes: []string{"openid", "admin"}, wantErr: true},
+		{name: "Client credentials", grantTypes: []string{"client_credentials"}, wantErr: true},
+		{name: "Resource server", resourceServer: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{clientStore: &clientStoreStub{}, scopeStore: scopes}
+			c := store.Client{AllowedScopes: tt.scopes, GrantTypes: tt.grantTypes, ResourceServer: tt.resourceServer}
+			_, _, err := s.RegisterOpen(context.Background(), c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RegisterOpen() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// Administrators may allow any registered scope and grant type, and may register
+			// resource servers.
+			if _, _, err = s.Register(context.Background(), c); err != nil {
+				t.Errorf("Register() error = %v", err)
+			}
+		})
+	}
+}
+
+func Test_validateGrantTypes(t *testing.T) {
+	tests := []struct {
+		name          string
+		clientType    store.ClientType
+		grantTypes    []string
+		responseTypes []string
+		wantErr       bool
+	}{
+		{
+			name:          "Authorization code with refresh",
+			clientType:    store.ConfidentialClient,
+			grantTypes:    []string{"authorization_code", "refresh_token"},
+			responseTypes: []string{"code"},
+			wantErr:       false,
+		},
+		{
+			name:          "Client credentials only",
+			clientType:    store.ConfidentialClient,
+			grantTypes:    []string{"client_credentials"},
+			responseTypes: nil,
+			wantErr:       false,
+		},
+		{
+			name:          "No grant types",
+			clientType:    store.ConfidentialClient,
+			grantTypes:    nil,
+			responseTypes: nil,
+			wantErr:       true,
+		},
+		{
+			name:          "Unsupported grant type",
+			clientType:    store.ConfidentialClient,
+			grantTypes:    []string{"password"},
+			responseTypes: nil,
+			wantErr:       true,
+		},
+		{
+			name:          "Unsupported response type",
+			clientType:    store.ConfidentialClient,
+			grantTypes:    []string{"authorization_code"},
+			responseTypes: []string{"code", "token"},
+			wantErr:       true,
+		},
+		{
+			name:          "Authorization code without code response type",
+			clientType:    store.ConfidentialClient,
+			grantTypes:    []string{"authorization_code"},
+			responseTypes: nil,
+			wantErr:       true,
+		},
+		{
+			name:          "Code response type without authorization code",
+			clientType:    store.ConfidentialClient,
+			grantTypes:    []string{"client_credentials"},
+			responseTypes: []string{"code"},
+			wantErr:       true,
+		},
+		{
+			name:          "Refresh token without authorization code",
+			clientType:    store.ConfidentialClient,
+			grantTypes:    []string{"client_credentials", "refresh_token"},
+			responseTypes: nil,
+			wantErr:       true,
+		},
+		{
+			name:          "Public client with client credentials",
+			clientType:    store.PublicClient,
+			grantTypes:    []string{"authorization_code", "client_credentials"},
+			responseTypes: []string{"code"},
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateGrantTypes(tt.clientType, tt.grantTypes, tt.responseTypes)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateGrantTypes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
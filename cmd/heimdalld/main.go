@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/sqlite3"
@@ -12,7 +15,9 @@ import (
 	"github.com/mattmeyers/heimdall/auth"
 	"github.com/mattmeyers/heimdall/client"
 	"github.com/mattmeyers/heimdall/http"
+	"github.com/mattmeyers/heimdall/scope"
 	"github.com/mattmeyers/heimdall/store"
+	"github.com/mattmeyers/heimdall/store/file"
 	"github.com/mattmeyers/heimdall/store/sqlite"
 	"github.com/mattmeyers/heimdall/user"
 	"github.com/mattmeyers/level"
@@ -62,28 +67,87 @@ func run(args []string) error {
 
 	userController := &http.UserController{Service: *userService}
 
-	clientService, err := client.NewService(ss.clientStore)
+	// Tokens must remain verifiable until they expire, so they cannot outlive a retired key.
+	if flags.keyStore != "" && (flags.maxAccessTokenLifespan > flags.keyRetiredLife || flags.maxIDTokenLifespan > flags.keyRetiredLife) {
+		return errors.New("maximum access and ID token lifespans must not exceed the retired key lifespan")
+	}
+
+	clientService, err := client.NewService(ss.clientStore, ss.scopeStore, flags.secretGracePeriod, store.TokenLifespans{
+		AccessToken:  int(flags.maxAccessTokenLifespan / time.Second),
+		RefreshToken: int(flags.maxRefreshTokenLifespan / time.Second),
+		IDToken:      int(flags.maxIDTokenLifespan / time.Second),
+		AuthCode:     int(flags.maxAuthCodeLifespan / time.Second),
+	})
 	if err != nil {
 		return err
 	}
 
-	clientController := &http.ClientController{Service: *clientService}
+	clientController := &http.ClientController{Service: *clientService, AdminToken: flags.adminToken}
+
+	if !flags.noMigrate {
+		n, err := clientService.HashPlaintextSecrets(context.Background())
+		if err != nil {
+			return err
+		} else if n > 0 {
+			logger.Info("Hashed %d plaintext client secrets", n)
+		}
+	}
+
+	scopeService, err := scope.NewService(ss.scopeStore)
+	if err != nil {
+		return err
+	}
+
+	scopeController := &http.ScopeController{Service: *scopeService, AdminToken: flags.adminToken}
+
+	jwtSettings, err := getJWTSettings(flags.issuer, flags.jwtKeyFiles)
+	if err != nil {
+		return err
+	}
+
+	if flags.keyStore != "" {
+		keyManager, err := getKeyManager(flags, ss)
+		if err != nil {
+			return err
+		}
+
+		if flags.rotateKeys {
+			logger.Info("Rotating signing keys")
+			return keyManager.Rotate(context.Background())
+		}
+
+		jwtSettings.Keys = keyManager.KeySet()
+		jwtSettings.Algorithm = keyManager.KeySet().Active().Algorithm
+
+		go keyManager.Run(context.Background(), time.Minute, func(err error) {
+			logger.Error("Signing key reload failed: %s", err)
+		})
+	}
 
 	authService, err := auth.NewService(
 		ss.userStore,
 		ss.clientStore,
 		ss.authCodeStore,
-		auth.JWTSettings{
-			Issuer:     "heimdall",
-			Lifespan:   3600,
-			SigningKey: "so-secret-wow",
-			Algorithm:  auth.HMAC256Algorithm,
+		ss.refreshTokenStore,
+		ss.revokedTokenStore,
+		ss.scopeStore,
+		ss.consentStore,
+		jwtSettings,
+		auth.RefreshTokenSettings{
+			Lifespan: 30 * 24 * 3600,
+		},
+		auth.AuthCodeSettings{
+			Lifespan: 3600,
 		},
 	)
 	if err != nil {
 		return err
 	}
 
+	go authService.RunPruner(context.Background(), time.Hour, func(err error) {
+		logger.Error("Pruning expired records failed: %s", err)
+	})
+
 	authController := &http.AuthController{Service: *authService}
 
 	s, err := http.NewServer(":8080", logger)
@@ -91,7 +155,7 @@ func run(args []string) error {
 		return err
 	}
 
-	s.RegisterRoutes(userController, clientController, authController)
+	s.RegisterRoutes(userController, clientController, scopeController, authController)
 
 	return s.ListenAndServe()
 }
@@ -100,6 +164,23 @@ type flags struct {
 	storeDriver string
 	logLevel    string
 	noMigrate   bool
+	issuer      string
+	jwtKeyFiles string
+
+	secretGracePeriod time.Duration
+	adminToken        string
+
+	maxAccessTokenLifespan  time.Duration
+	maxRefreshTokenLifespan time.Duration
+	maxIDTokenLifespan      time.Duration
+	maxAuthCodeLifespan     time.Duration
+
+	keyStore       string
+	keyDir         string
+	keyAlgorithm   string
+	keyRotation    time.Duration
+	keyRetiredLife time.Duration
+	rotateKeys     bool
 }
 
 func initializeFlags() flags {
@@ -108,16 +189,90 @@ func initializeFlags() flags {
 	flag.StringVar(&fs.storeDriver, "driver", "mem", "Database driver: mem, sqlite")
 	flag.BoolVar(&fs.noMigrate, "no-migrate", false, "Prevent migrating db. Ignored for mem driver.")
 	flag.StringVar(&fs.logLevel, "log-level", "info", "Min log level: debug, info, warn, error, fatal")
+	flag.StringVar(&fs.issuer, "issuer", "http://localhost:8080", "Issuer URL placed in tokens. Endpoints in the server metadata are relative to it.")
+	flag.StringVar(&fs.jwtKeyFiles, "jwt-keys", "", "Comma separated PEM private key files used to sign JWTs. The first key is active. Uses HS256, which cannot sign ID tokens, if empty.")
+	flag.DurationVar(&fs.secretGracePeriod, "client-secret-grace-period", 24*time.Hour, "How long a client's previous secret remains valid after rotation.")
+	flag.StringVar(&fs.adminToken, "admin-token", os.Getenv("HEIMDALL_ADMIN_TOKEN"), "Bearer token required by the client and scope administration endpoints. Defaults to $HEIMDALL_ADMIN_TOKEN. The endpoints are disabled if empty.")
+	flag.DurationVar(&fs.maxAccessTokenLifespan, "max-access-token-lifespan", 24*time.Hour, "Longest access token lifespan a client can be configured with. Must not exceed -key-retired-lifespan when using -key-store.")
+	flag.DurationVar(&fs.maxRefreshTokenLifespan, "max-refresh-token-lifespan", 365*24*time.Hour, "Longest refresh token lifespan a client can be configured with.")
+	flag.DurationVar(&fs.maxIDTokenLifespan, "max-id-token-lifespan", 24*time.Hour, "Longest ID token lifespan a client can be configured with. Must not exceed -key-retired-lifespan when using -key-store.")
+	flag.DurationVar(&fs.maxAuthCodeLifespan, "max-auth-code-lifespan", time.Hour, "Longest auth code lifespan a client can be configured with.")
+	flag.StringVar(&fs.keyStore, "key-store", "", "Rotated signing key store: sqlite, file. Overrides -jwt-keys.")
+	flag.StringVar(&fs.keyDir, "key-dir", "db/keys", "Directory used by the file key store.")
+	flag.StringVar(&fs.keyAlgorithm, "key-alg", "ES256", "Algorithm for generated signing keys: RS256, ES256, EdDSA")
+	flag.DurationVar(&fs.keyRotation, "key-rotation", 30*24*time.Hour, "How long a signing key is active before it is rotated.")
+	flag.DurationVar(&fs.keyRetiredLife, "key-retired-lifespan", 24*time.Hour, "How long a retired signing key can verify tokens.")
+	flag.BoolVar(&fs.rotateKeys, "rotate-keys", false, "Rotate the signing keys in the key store and exit.")
 
 	flag.Parse()
 
 	return fs
 }
 
+// getJWTSettings loads the provided key files into a key set. If no key files are
+// provided, tokens are signed using HS256.
+func getJWTSettings(issuer, keyFiles string) (auth.JWTSettings, error) {
+	settings := auth.JWTSettings{
+		Issuer:    issuer,
+		Lifespan:  3600,
+		ClockSkew: 30,
+	}
+
+	if keyFiles == "" {
+		settings.SigningKey = "so-secret-wow"
+		settings.Algorithm = auth.HMAC256Algorithm
+		return settings, nil
+	}
+
+	var keys []auth.Key
+	for _, path := range strings.Split(keyFiles, ",") {
+		k, err := auth.LoadKey(strings.TrimSpace(path))
+		if err != nil {
+			return auth.JWTSettings{}, fmt.Errorf("loading key %s: %w", path, err)
+		}
+		keys = append(keys, k)
+	}
+
+	settings.Keys = auth.NewKeySet(keys[0], keys[1:]...)
+	settings.Algorithm = keys[0].Algorithm
+
+	return settings, nil
+}
+
+func getKeyManager(fs flags, ss stores) (*auth.KeyManager, error) {
+	alg, err := auth.ParseSigningAlgorithm(fs.keyAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyStore store.SigningKeyStore
+	switch fs.keyStore {
+	case "sqlite":
+		keyStore = ss.signingKeyStore
+	case "file":
+		if keyStore, err = file.NewSigningKeyStore(fs.keyDir); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("unknown key store")
+	}
+
+	return auth.NewKeyManager(context.Background(), keyStore, auth.KeyRotationSettings{
+		Algorithm:       alg,
+		Interval:        fs.keyRotation,
+		RetiredLifespan: fs.keyRetiredLife,
+	})
+}
+
 type stores struct {
-	userStore     store.UserStore
-	clientStore   store.ClientStore
-	authCodeStore store.AuthCodeStore
+	userStore         store.UserStore
+	clientStore       store.ClientStore
+	authCodeStore     store.AuthCodeStore
+	refreshTokenStore store.RefreshTokenStore
+	revokedTokenStore store.RevokedTokenStore
+	scopeStore        store.ScopeStore
+	consentStore      store.ConsentStore
+	signingKeyStore   store.SigningKeyStore
 }
 
 func getSqliteStores(dsn string, noMigrate bool) (stores, error) {
@@ -160,5 +315,39 @@ func getSqliteStores(dsn string, noMigrate bool) (stores, error) {
 		return stores{}, err
 	}
 
-	return stores{userStore: userStore, clientStore: clientStore, authCodeStore: authCodeStore}, nil
+	refreshTokenStore, err := sqlite.NewRefreshTokenStore(db)
+	if err != nil {
+		return stores{}, err
+	}
+
+	signingKeyStore, err := sqlite.NewSigningKeyStore(db)
+	if err != nil {
+		return stores{}, err
+	}
+
+	revokedTokenStore, err := sqlite.NewRevokedTokenStore(db)
+	if err != nil {
+		return stores{}, err
+	}
+
+	scopeStore, err := sqlite.NewScopeStore(db)
+	if err != nil {
+		return stores{}, err
+	}
+
+	consentStore, err := sqlite.NewConsentStore(db)
+	if err != nil {
+		return stores{}, err
+	}
+
+	return stores{
+		userStore:         userStore,
+		clientStore:       clientStore,
+		authCodeStore:     authCodeStore,
+		refreshTokenStore: refreshTokenStore,
+		signingKeyStore:   signingKeyStore,
+		revokedTokenStore: revokedTokenStore,
+		scopeStore:        scopeStore,
+		consentStore:      consentStore,
+	}, nil
 }
@@ -58,6 +58,12 @@ func ValidatePassword(password, encodedHash string) (bool, error) {
 	return hashesAreEqual([]byte(hash), otherHash), nil
 }
 
+// IsPasswordHash determines if the string is a hash encoded by GetPasswordHash.
+func IsPasswordHash(s string) bool {
+	_, _, _, err := decodeHash(s)
+	return err == nil
+}
+
 func hashesAreEqual(a, b []byte) bool {
 	return subtle.ConstantTimeCompare(a, b) == 1
 }
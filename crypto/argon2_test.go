@@ -334,3 +334,34 @@ func TestGetPasswordHash(t *testing.T) {
 		})
 	}
 }
+
+func TestIsPasswordHash(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want bool
+	}{
+		{
+			name: "Encoded hash",
+			s:    "$argon2id$v=19$m=65536,t=3,p=4$sxCtsSYtbBo4tnUj6v7sCw$Vimp2o+sXuoqEOv09FQ6mWGJLAdc04ruejkNyyFGSPY",
+			want: true,
+		},
+		{
+			name: "Plaintext",
+			s:    "4f0c4fcbd5c1f1a7e0d7a1f7b9b3c2e1",
+			want: false,
+		},
+		{
+			name: "Empty",
+			s:    "",
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPasswordHash(tt.s); got != tt.want {
+				t.Errorf("IsPasswordHash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,4 @@
+ALTER TABLE client DROP COLUMN require_pkce;
+
+ALTER TABLE auth_code DROP COLUMN code_challenge_method;
+ALTER TABLE auth_code DROP COLUMN code_challenge;
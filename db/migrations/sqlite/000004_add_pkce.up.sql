@@ -0,0 +1,4 @@
+ALTER TABLE auth_code ADD COLUMN code_challenge VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE auth_code ADD COLUMN code_challenge_method VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE client ADD COLUMN require_pkce BOOLEAN NOT NULL DEFAULT 0;
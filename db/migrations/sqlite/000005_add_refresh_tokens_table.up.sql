@@ -0,0 +1,14 @@
+CREATE TABLE refresh_token (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    token VARCHAR NOT NULL UNIQUE,
+    family_id VARCHAR NOT NULL,
+    user_id INTEGER NOT NULL,
+    client_id VARCHAR NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME,
+    revoked_at DATETIME,
+    FOREIGN KEY(user_id) REFERENCES user(id)
+);
+
+CREATE INDEX refresh_token_family_id_idx ON refresh_token(family_id);
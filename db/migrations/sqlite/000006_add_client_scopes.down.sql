@@ -0,0 +1 @@
+DROP TABLE client_scope;
\ No newline at end of file
@@ -0,0 +1,7 @@
+CREATE TABLE client_scope (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    client_id INTEGER NOT NULL,
+    scope VARCHAR NOT NULL,
+    FOREIGN KEY(client_id) REFERENCES client(id),
+    UNIQUE(client_id, scope)
+);
@@ -0,0 +1,11 @@
+DROP TABLE auth_code;
+
+CREATE TABLE auth_code (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    code VARCHAR NOT NULL UNIQUE,
+    created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    code_challenge VARCHAR NOT NULL DEFAULT '',
+    code_challenge_method VARCHAR NOT NULL DEFAULT '',
+    FOREIGN KEY(user_id) REFERENCES user(id)
+);
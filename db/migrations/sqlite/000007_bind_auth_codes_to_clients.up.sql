@@ -0,0 +1,15 @@
+-- Auth codes are short lived, so the table is recreated rather than migrated. This also
+-- changes created_at to a DATETIME column so it can be scanned into a time.
+DROP TABLE auth_code;
+
+CREATE TABLE auth_code (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    client_id VARCHAR NOT NULL,
+    redirect_url VARCHAR NOT NULL,
+    code VARCHAR NOT NULL UNIQUE,
+    code_challenge VARCHAR NOT NULL DEFAULT '',
+    code_challenge_method VARCHAR NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY(user_id) REFERENCES user(id)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE refresh_token DROP COLUMN scope;
+
+ALTER TABLE auth_code DROP COLUMN token_family_id;
+ALTER TABLE auth_code DROP COLUMN consumed_at;
+ALTER TABLE auth_code DROP COLUMN scope;
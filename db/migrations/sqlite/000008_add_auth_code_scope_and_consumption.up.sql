@@ -0,0 +1,5 @@
+ALTER TABLE auth_code ADD COLUMN scope VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE auth_code ADD COLUMN consumed_at DATETIME;
+ALTER TABLE auth_code ADD COLUMN token_family_id VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE refresh_token ADD COLUMN scope VARCHAR NOT NULL DEFAULT '';
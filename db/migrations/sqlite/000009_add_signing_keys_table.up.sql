@@ -0,0 +1,8 @@
+CREATE TABLE signing_key (
+    id VARCHAR PRIMARY KEY,
+    state VARCHAR NOT NULL,
+    private_key BLOB NOT NULL,
+    created_at DATETIME NOT NULL,
+    activated_at DATETIME,
+    retired_at DATETIME
+);
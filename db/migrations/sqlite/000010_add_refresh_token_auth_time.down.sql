@@ -0,0 +1 @@
+ALTER TABLE refresh_token DROP COLUMN auth_time;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE refresh_token ADD COLUMN auth_time DATETIME;
+
+UPDATE refresh_token SET auth_time = created_at;
@@ -0,0 +1 @@
+ALTER TABLE auth_code DROP COLUMN nonce;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE auth_code ADD COLUMN nonce VARCHAR NOT NULL DEFAULT '';
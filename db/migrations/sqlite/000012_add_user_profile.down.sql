@@ -0,0 +1,2 @@
+ALTER TABLE user DROP COLUMN name;
+ALTER TABLE user DROP COLUMN email_verified;
@@ -0,0 +1,2 @@
+ALTER TABLE user ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE user ADD COLUMN name VARCHAR NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+DROP TABLE revoked_token;
\ No newline at end of file
@@ -0,0 +1,7 @@
+CREATE TABLE revoked_token (
+    token_id VARCHAR PRIMARY KEY,
+    revoked_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL
+);
+
+CREATE INDEX revoked_token_expires_at_idx ON revoked_token(expires_at);
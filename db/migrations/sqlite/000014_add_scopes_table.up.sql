@@ -0,0 +1,13 @@
+CREATE TABLE scope (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR NOT NULL UNIQUE,
+    description VARCHAR NOT NULL DEFAULT '',
+    requires_consent BOOLEAN NOT NULL DEFAULT 0
+);
+
+INSERT INTO scope (name, description, requires_consent) VALUES
+    ('openid', 'Sign in using your account', 0),
+    ('email', 'View your email address', 1),
+    ('profile', 'View your name', 1);
+
+INSERT OR IGNORE INTO scope (name) SELECT DISTINCT scope FROM client_scope;
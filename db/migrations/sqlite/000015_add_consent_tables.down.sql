@@ -0,0 +1,2 @@
+DROP TABLE consent_request;
+DROP TABLE consent_grant;
\ No newline at end of file
@@ -0,0 +1,24 @@
+CREATE TABLE consent_grant (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    client_id VARCHAR NOT NULL,
+    scope VARCHAR NOT NULL,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY(user_id) REFERENCES user(id),
+    UNIQUE(user_id, client_id, scope)
+);
+
+CREATE TABLE consent_request (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    challenge VARCHAR NOT NULL UNIQUE,
+    user_id INTEGER NOT NULL,
+    client_id VARCHAR NOT NULL,
+    redirect_url VARCHAR NOT NULL,
+    state VARCHAR NOT NULL DEFAULT '',
+    scope VARCHAR NOT NULL DEFAULT '',
+    nonce VARCHAR NOT NULL DEFAULT '',
+    code_challenge VARCHAR NOT NULL DEFAULT '',
+    code_challenge_method VARCHAR NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY(user_id) REFERENCES user(id)
+);
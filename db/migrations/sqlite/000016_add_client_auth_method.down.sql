@@ -0,0 +1 @@
+ALTER TABLE client DROP COLUMN token_endpoint_auth_method;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- Existing clients have always sent their credentials in the request body.
+ALTER TABLE client ADD COLUMN token_endpoint_auth_method VARCHAR NOT NULL DEFAULT 'client_secret_post';
@@ -0,0 +1 @@
+ALTER TABLE client RENAME COLUMN secret_hash TO client_secret;
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- Existing plaintext secrets are hashed by the server on startup, since the hashing cannot be
+-- done in SQL.
+ALTER TABLE client RENAME COLUMN client_secret TO secret_hash;
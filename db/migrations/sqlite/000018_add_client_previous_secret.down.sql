@@ -0,0 +1,2 @@
+ALTER TABLE client DROP COLUMN previous_secret_expires_at;
+ALTER TABLE client DROP COLUMN previous_secret_hash;
\ No newline at end of file
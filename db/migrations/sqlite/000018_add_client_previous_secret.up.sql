@@ -0,0 +1,2 @@
+ALTER TABLE client ADD COLUMN previous_secret_hash VARCHAR;
+ALTER TABLE client ADD COLUMN previous_secret_expires_at DATETIME;
@@ -0,0 +1,2 @@
+ALTER TABLE client DROP COLUMN disabled;
+ALTER TABLE client DROP COLUMN name;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE client ADD COLUMN name VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE client ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE client DROP COLUMN client_type;
\ No newline at end of file
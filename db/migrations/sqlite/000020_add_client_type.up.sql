@@ -0,0 +1 @@
+ALTER TABLE client ADD COLUMN client_type VARCHAR NOT NULL DEFAULT 'confidential';
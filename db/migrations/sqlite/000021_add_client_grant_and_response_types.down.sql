@@ -0,0 +1,2 @@
+ALTER TABLE client DROP COLUMN response_types;
+ALTER TABLE client DROP COLUMN grant_types;
\ No newline at end of file
@@ -0,0 +1,10 @@
+ALTER TABLE client ADD COLUMN grant_types VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE client ADD COLUMN response_types VARCHAR NOT NULL DEFAULT '';
+
+-- Existing clients keep every flow they could already use.
+UPDATE client SET
+    grant_types = CASE client_type
+        WHEN 'public' THEN 'authorization_code refresh_token'
+        ELSE 'authorization_code refresh_token client_credentials'
+    END,
+    response_types = 'code';
@@ -0,0 +1,4 @@
+ALTER TABLE client DROP COLUMN auth_code_lifespan;
+ALTER TABLE client DROP COLUMN id_token_lifespan;
+ALTER TABLE client DROP COLUMN refresh_token_lifespan;
+ALTER TABLE client DROP COLUMN access_token_lifespan;
\ No newline at end of file
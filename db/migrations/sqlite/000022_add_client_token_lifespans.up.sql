@@ -0,0 +1,5 @@
+-- Lifespans are in seconds. Zero uses the server default.
+ALTER TABLE client ADD COLUMN access_token_lifespan INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE client ADD COLUMN refresh_token_lifespan INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE client ADD COLUMN id_token_lifespan INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE client ADD COLUMN auth_code_lifespan INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE auth_code DROP COLUMN access_token_expires_at;
+ALTER TABLE auth_code DROP COLUMN access_token_id;
\ No newline at end of file
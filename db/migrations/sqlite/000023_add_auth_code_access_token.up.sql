@@ -0,0 +1,2 @@
+ALTER TABLE auth_code ADD COLUMN access_token_id VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE auth_code ADD COLUMN access_token_expires_at DATETIME;
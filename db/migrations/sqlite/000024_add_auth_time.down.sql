@@ -0,0 +1,3 @@
+ALTER TABLE consent_request DROP COLUMN auth_time;
+
+ALTER TABLE auth_code DROP COLUMN auth_time;
\ No newline at end of file
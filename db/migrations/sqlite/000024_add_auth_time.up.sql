@@ -0,0 +1,5 @@
+ALTER TABLE auth_code ADD COLUMN auth_time DATETIME;
+UPDATE auth_code SET auth_time = created_at;
+
+ALTER TABLE consent_request ADD COLUMN auth_time DATETIME;
+UPDATE consent_request SET auth_time = created_at;
@@ -0,0 +1 @@
+ALTER TABLE user DROP COLUMN tokens_revoked_at;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE user ADD COLUMN tokens_revoked_at DATETIME;
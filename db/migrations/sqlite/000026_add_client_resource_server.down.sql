@@ -0,0 +1 @@
+ALTER TABLE client DROP COLUMN resource_server;
\ No newline at end of file
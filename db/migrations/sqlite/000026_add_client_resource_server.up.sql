@@ -0,0 +1,2 @@
+-- Resource servers may introspect access tokens issued to other clients.
+ALTER TABLE client ADD COLUMN resource_server BOOLEAN NOT NULL DEFAULT 0;
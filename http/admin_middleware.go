@@ -0,0 +1,39 @@
+package http
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// newAdminMiddleware restricts a handler to requests bearing the admin token. If no admin
+// token is configured, every request is rejected so that the admin API is disabled rather
+// than left open.
+func newAdminMiddleware(token string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				http.Error(w, "admin API is disabled", http.StatusForbidden)
+				return
+			}
+
+			if !hasAdminToken(r, token) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "invalid admin token", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// hasAdminToken reports whether the request bears the admin token. It is always false if no
+// admin token is configured.
+func hasAdminToken(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+
+	bearer, err := getBearerToken(r)
+	return err == nil && subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1
+}
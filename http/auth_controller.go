@@ -1,25 +1,52 @@
 package http
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/mattmeyers/heimdall/auth"
+	"github.com/mattmeyers/heimdall/store"
 )
 
 type AuthController struct {
 	Service auth.Service
 }
 
+// The paths of the endpoints advertised in the server metadata.
+const (
+	authorizationPath = "/auth"
+	tokenPath         = "/oauth/token"
+	revocationPath    = "/oauth/revoke"
+	introspectionPath = "/oauth/introspect"
+	jwksPath          = "/.well-known/jwks.json"
+	userInfoPath      = "/userinfo"
+)
+
 func (c *AuthController) Register(router *httprouter.Router) {
-	router.HandlerFunc(http.MethodGet, "/auth", c.handleAuth)
-	router.HandlerFunc(http.MethodPost, "/oauth/token", c.handleToken)
+	router.HandlerFunc(http.MethodGet, authorizationPath, c.handleAuth)
+	router.HandlerFunc(http.MethodPost, "/login", c.handleAuthCodeLogin)
+	router.HandlerFunc(http.MethodPost, "/consent", c.handleConsent)
+	router.HandlerFunc(http.MethodGet, "/consents", c.handleListConsents)
+	router.HandlerFunc(http.MethodDelete, "/consents/:client_id", c.handleRevokeConsent)
+	router.HandlerFunc(http.MethodPost, tokenPath, c.handleToken)
+	router.HandlerFunc(http.MethodPost, revocationPath, c.handleRevoke)
+	router.HandlerFunc(http.MethodPost, introspectionPath, c.handleIntrospect)
 	router.Handler(http.MethodPost, "/auth/register", c.handleRegister())
 	router.Handler(http.MethodPost, "/auth/login", c.handleLogin())
 	router.Handler(http.MethodGet, "/auth/validate", c.handleValidate())
+	router.HandlerFunc(http.MethodPost, "/auth/revoke", c.handleRevokeUserTokens)
+	router.HandlerFunc(http.MethodGet, jwksPath, c.handleJWKS)
+	router.HandlerFunc(http.MethodGet, userInfoPath, c.handleUserInfo)
+	router.HandlerFunc(http.MethodPost, userInfoPath, c.handleUserInfo)
+	router.HandlerFunc(http.MethodGet, "/.well-known/openid-configuration", c.handleMetadata)
+	router.HandlerFunc(http.MethodGet, "/.well-known/oauth-authorization-server", c.handleMetadata)
 }
 
 func (c *AuthController) handleLogin() http.Handler {
@@ -102,78 +129,373 @@ func generateLoginRedirect(redirectURL string, token string) (string, error) {
 }
 
 func (c *AuthController) handleAuth(w http.ResponseWriter, r *http.Request) {
-	switch r.URL.Query().Get("response_type") {
-	case "code":
-		clientID := r.URL.Query().Get("client_id")
-		redirectURL := r.URL.Query().Get("redirect_url")
+	req := getAuthCodeRequest(r.URL.Query())
 
-		tmpl, err := c.Service.AuthCodeFlow(r.Context(), clientID, redirectURL)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
+	tmpl, err := c.Service.AuthCodeFlow(r.Context(), req)
+	if err != nil {
+		writeAuthorizationError(w, r, req, err)
+		return
+	}
 
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		w.WriteHeader(200)
-		w.Write(tmpl)
-	default:
-		http.Error(w, "missing or invalid response_type", http.StatusBadRequest)
+	writeHTMLPage(w, tmpl)
+}
+
+// writeHTMLPage writes a page that collects the user's credentials or consent. The page may
+// not be framed by other sites, which could otherwise trick the user into submitting it
+// (RFC 6749 section 10.13).
+func writeHTMLPage(w http.ResponseWriter, page []byte) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.WriteHeader(200)
+	w.Write(page)
+}
+
+// getAuthCodeRequest reads the authorization request parameters. The redirect URL is read
+// from redirect_uri as defined by RFC 6749, falling back to the redirect_url parameter
+// originally used by heimdall.
+func getAuthCodeRequest(params url.Values) auth.AuthCodeRequest {
+	redirectURL := params.Get("redirect_uri")
+	if redirectURL == "" {
+		redirectURL = params.Get("redirect_url")
+	}
+
+	return auth.AuthCodeRequest{
+		ResponseType:        params.Get("response_type"),
+		ClientID:            params.Get("client_id"),
+		RedirectURL:         redirectURL,
+		State:               params.Get("state"),
+		Scope:               params.Get("scope"),
+		Nonce:               params.Get("nonce"),
+		CodeChallenge:       params.Get("code_challenge"),
+		CodeChallengeMethod: params.Get("code_challenge_method"),
+	}
+}
+
+// handleAuthCodeLogin handles the sign in form rendered by the authorization endpoint. On
+// success, the user agent is redirected back to the client with an authorization code, or
+// shown the consent screen if the user has not yet granted the requested scopes.
+func (c *AuthController) handleAuthCodeLogin(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "malformed request body", http.StatusBadRequest)
+		return
+	}
+
+	req := getAuthCodeRequest(r.PostForm)
+
+	grant, err := c.Service.GrantAuthCode(
+		r.Context(),
+		r.PostForm.Get("email"),
+		r.PostForm.Get("password"),
+		req,
+	)
+	var oauthErr *auth.Error
+	if errors.As(err, &oauthErr) {
+		writeAuthorizationError(w, r, req, err)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if grant.ConsentPage != nil {
+		writeHTMLPage(w, grant.ConsentPage)
 		return
 	}
+
+	redirect, err := generateAuthCodeRedirect(req.RedirectURL, grant.Code, req.State)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+// handleConsent handles the user's answer to the consent screen. Either way, the user agent
+// is redirected back to the client, with an access_denied error if the user declined.
+func (c *AuthController) handleConsent(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "malformed request body", http.StatusBadRequest)
+		return
+	}
+
+	req, code, err := c.Service.Consent(
+		r.Context(),
+		r.PostForm.Get("challenge"),
+		r.PostForm.Get("decision") == "approve",
+	)
+
+	var oauthErr *auth.Error
+	if errors.As(err, &oauthErr) {
+		writeAuthorizationError(w, r, req, err)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	redirect, err := generateAuthCodeRedirect(req.RedirectURL, code, req.State)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (c *AuthController) handleListConsents(w http.ResponseWriter, r *http.Request) {
+	token, err := getBearerToken(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	grants, err := c.Service.ConsentGrants(r.Context(), token)
+	if errors.Is(err, auth.ErrInsufficientScope) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	} else if err != nil {
+		writeInvalidTokenError(w, err)
+		return
+	}
+
+	if grants == nil {
+		grants = []store.ConsentGrant{}
+	}
+
+	out, err := json.Marshal(grants)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
+
+func (c *AuthController) handleRevokeConsent(w http.ResponseWriter, r *http.Request) {
+	token, err := getBearerToken(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	clientID := httprouter.ParamsFromContext(r.Context()).ByName("client_id")
+
+	err = c.Service.RevokeConsent(r.Context(), token, clientID)
+	if errors.Is(err, auth.ErrInsufficientScope) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	} else if err != nil {
+		writeInvalidTokenError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// generateAuthErrorRedirect builds the redirect informing the client that the authorization
+// request failed (RFC 6749 section 4.1.2.1).
+func generateAuthErrorRedirect(redirectURL string, oauthErr *auth.Error, state string) (string, error) {
+	u, err := url.Parse(redirectURL)
+	if err != nil {
+		return "", err
+	}
+
+	params := u.Query()
+
+	params.Set("error", string(oauthErr.Code))
+	if oauthErr.Description != "" {
+		params.Set("error_description", oauthErr.Description)
+	}
+	if oauthErr.URI != "" {
+		params.Set("error_uri", oauthErr.URI)
+	}
+	if state != "" {
+		params.Set("state", state)
+	}
+
+	u.RawQuery = params.Encode()
+
+	return u.String(), nil
+}
+
+func generateAuthCodeRedirect(redirectURL, code, state string) (string, error) {
+	u, err := url.Parse(redirectURL)
+	if err != nil {
+		return "", err
+	}
+
+	params := u.Query()
+
+	params.Set("code", code)
+	if state != "" {
+		params.Set("state", state)
+	}
+
+	u.RawQuery = params.Encode()
+
+	return u.String(), nil
 }
 
 type tokenRequestBody struct {
+	GrantType    string `json:"grant_type"`
 	ClientID     string `json:"client_id"`
 	ClientSecret string `json:"client_secret"`
 	RedirectURL  string `json:"redirect_uri"`
 	AuthCode     string `json:"code"`
+	CodeVerifier string `json:"code_verifier"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+// getTokenRequestBody reads a token request. Requests are form encoded as required by
+// RFC 6749, but JSON bodies are still accepted from clients that send the grant type in
+// the query string or set a JSON content type.
+func getTokenRequestBody(r *http.Request) (tokenRequestBody, error) {
+	grantType := r.URL.Query().Get("grant_type")
+	if grantType != "" || strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body tokenRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return tokenRequestBody{}, err
+		}
+
+		if body.GrantType == "" {
+			body.GrantType = grantType
+		}
+
+		return body, nil
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return tokenRequestBody{}, err
+	}
+
+	return tokenRequestBody{
+		GrantType:    r.PostFormValue("grant_type"),
+		ClientID:     r.PostFormValue("client_id"),
+		ClientSecret: r.PostFormValue("client_secret"),
+		RedirectURL:  r.PostFormValue("redirect_uri"),
+		AuthCode:     r.PostFormValue("code"),
+		CodeVerifier: r.PostFormValue("code_verifier"),
+		RefreshToken: r.PostFormValue("refresh_token"),
+		Scope:        r.PostFormValue("scope"),
+	}, nil
+}
+
+// getClientAuth determines the credentials the client authenticated with. Credentials in
+// the Authorization header use client_secret_basic, and are form encoded before being
+// base64 encoded (RFC 6749 section 2.3.1). Otherwise the credentials from the request body
+// are used with client_secret_post, or none if only a client_id was sent by a public
+// client. A client may only use one method per request.
+func getClientAuth(r *http.Request, clientID, clientSecret string) (auth.ClientAuth, error) {
+	id, secret, ok := r.BasicAuth()
+	if !ok && clientSecret == "" {
+		return auth.ClientAuth{ClientID: clientID, Method: store.ClientAuthNone}, nil
+	} else if !ok {
+		return auth.ClientAuth{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Method:       store.ClientSecretPost,
+		}, nil
+	}
+
+	if clientSecret != "" {
+		return auth.ClientAuth{}, &auth.Error{
+			Code:        auth.InvalidRequest,
+			Description: "multiple client authentication methods used",
+		}
+	}
+
+	id, idErr := url.QueryUnescape(id)
+	secret, secretErr := url.QueryUnescape(secret)
+	if idErr != nil || secretErr != nil {
+		return auth.ClientAuth{}, auth.ErrInvalidClient
+	}
+
+	if clientID != "" && clientID != id {
+		return auth.ClientAuth{}, &auth.Error{
+			Code:        auth.InvalidRequest,
+			Description: "client_id does not match the authenticated client",
+		}
+	}
+
+	return auth.ClientAuth{
+		ClientID:     id,
+		ClientSecret: secret,
+		Method:       store.ClientSecretBasic,
+	}, nil
 }
 
 type tokenResponseBody struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
 	TokenType    string `json:"token_type"`
-	Expires      int    `json:"expires"`
+	ExpiresIn    int    `json:"expires_in"`
+	Scope        string `json:"scope,omitempty"`
 }
 
 func (c *AuthController) handleToken(w http.ResponseWriter, r *http.Request) {
-	switch r.URL.Query().Get("grant_type") {
-	case "authorization_code":
-		var body tokenRequestBody
-		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-			http.Error(w, "malformed request body", http.StatusBadRequest)
-			return
-		}
+	body, err := getTokenRequestBody(r)
+	if err != nil {
+		writeOAuthError(w, &auth.Error{Code: auth.InvalidRequest, Description: "malformed request body"})
+		return
+	}
+
+	client, err := getClientAuth(r, body.ClientID, body.ClientSecret)
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
 
-		token, err := c.Service.ConvertCodeToToken(
+	var token auth.Token
+	switch body.GrantType {
+	case auth.AuthorizationCodeGrant:
+		token, err = c.Service.ConvertCodeToToken(
 			r.Context(),
 			body.AuthCode,
-			body.ClientID,
-			body.ClientSecret,
+			client,
 			body.RedirectURL,
+			body.CodeVerifier,
 		)
-		if err != nil {
-			http.Error(w, "invalid auth code", http.StatusUnauthorized)
-			return
-		}
-
-		out, err := json.Marshal(tokenResponseBody{
-			AccessToken: token.AccessToken,
-			TokenType:   "bearer",
-			Expires:     token.Lifespan,
-		})
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		w.WriteHeader(200)
-		w.Write(out)
+	case auth.RefreshTokenGrant:
+		token, err = c.Service.RefreshToken(r.Context(), body.RefreshToken, client, body.Scope)
+	case auth.ClientCredentialsGrant:
+		token, err = c.Service.ClientCredentials(r.Context(), client, body.Scope)
+	case "":
+		err = &auth.Error{Code: auth.InvalidRequest, Description: "missing grant_type"}
 	default:
-		http.Error(w, "missing or invalid grant_type", http.StatusBadRequest)
+		err = &auth.Error{Code: auth.UnsupportedGrantType}
+	}
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+
+	out, err := json.Marshal(tokenResponseBody{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		IDToken:      token.IDToken,
+		TokenType:    "bearer",
+		ExpiresIn:    token.Lifespan,
+		Scope:        token.Scope,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(200)
+	w.Write(out)
 }
 
 func (c *AuthController) handleRegister() http.Handler {
@@ -202,21 +524,15 @@ func (c *AuthController) handleRegister() http.Handler {
 }
 func (c *AuthController) handleValidate() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "missing Authorization header", http.StatusUnauthorized)
-			return
-		}
-
-		bearer, token, ok := strings.Cut(authHeader, " ")
-		if !ok || bearer != "Bearer" {
-			http.Error(w, "malformed Authorization header", http.StatusUnauthorized)
+		token, err := getBearerToken(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
 
-		err := c.Service.ValidateToken(r.Context(), token)
+		_, err = c.Service.ValidateToken(r.Context(), token)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeInvalidTokenError(w, err)
 			return
 		}
 
@@ -224,3 +540,290 @@ func (c *AuthController) handleValidate() http.Handler {
 		w.Write(nil)
 	})
 }
+
+// handleRevokeUserTokens signs the user out of every device by revoking the tokens they
+// obtained from /auth/login.
+func (c *AuthController) handleRevokeUserTokens(w http.ResponseWriter, r *http.Request) {
+	token, err := getBearerToken(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	err = c.Service.RevokeUserTokens(r.Context(), token)
+	if errors.Is(err, auth.ErrInsufficientScope) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	} else if err != nil {
+		writeInvalidTokenError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getBearerToken extracts the access token from the Authorization header. Form encoded
+// POST requests may instead provide it in the access_token parameter (RFC 6750 section 2.2).
+func getBearerToken(r *http.Request) (string, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		if r.Method == http.MethodPost &&
+			strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			if token := r.PostFormValue("access_token"); token != "" {
+				return token, nil
+			}
+		}
+
+		return "", errors.New("missing Authorization header")
+	}
+
+	bearer, token, ok := strings.Cut(authHeader, " ")
+	if !ok || bearer != "Bearer" {
+		return "", errors.New("malformed Authorization header")
+	}
+
+	return token, nil
+}
+
+func (c *AuthController) handleUserInfo(w http.ResponseWriter, r *http.Request) {
+	token, err := getBearerToken(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	info, err := c.Service.UserInfo(r.Context(), token)
+	if errors.Is(err, auth.ErrInsufficientScope) {
+		w.Header().Set(
+			"WWW-Authenticate",
+			fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, auth.OpenIDScope),
+		)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	} else if err != nil {
+		writeInvalidTokenError(w, err)
+		return
+	}
+
+	out, err := json.Marshal(info)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
+
+// writeInvalidTokenError responds to a request bearing a token that failed validation. The
+// WWW-Authenticate header follows RFC 6750 so that clients can tell an expired token, which
+// should be refreshed, apart from one that will never be accepted.
+func writeInvalidTokenError(w http.ResponseWriter, err error) {
+	var description string
+	switch {
+	case errors.Is(err, auth.ErrTokenExpired):
+		description = "the access token expired"
+	case errors.Is(err, auth.ErrTokenNotYetValid):
+		description = "the access token is not valid yet"
+	case errors.Is(err, auth.ErrInvalidSignature):
+		description = "the access token signature is invalid"
+	case errors.Is(err, auth.ErrInvalidIssuer):
+		description = "the access token was issued by an unknown issuer"
+	case errors.Is(err, auth.ErrInvalidAudience):
+		description = "the access token is not intended for this audience"
+	case errors.Is(err, auth.ErrMalformedToken):
+		description = "the access token is malformed"
+	case errors.Is(err, auth.ErrTokenRevoked):
+		description = "the access token has been revoked"
+	case errors.Is(err, auth.ErrInvalidTokenType):
+		description = "the token is not an access token"
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(
+		"WWW-Authenticate",
+		fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, description),
+	)
+	http.Error(w, description, http.StatusUnauthorized)
+}
+
+// jwksMaxAge is the number of seconds verifiers may cache the JWK Set. Keys are published
+// before they become active, so this only needs to be shorter than the rotation overlap.
+const jwksMaxAge = 900
+
+func (c *AuthController) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	out, err := json.Marshal(c.Service.PublicKeys(r.Context()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(out)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", jwksMaxAge))
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
+
+type tokenTypeHintBody struct {
+	Token         string `json:"token"`
+	TokenTypeHint string `json:"token_type_hint"`
+	ClientID      string `json:"client_id"`
+	ClientSecret  string `json:"client_secret"`
+}
+
+func getTokenTypeHintBody(r *http.Request) (tokenTypeHintBody, error) {
+	var body tokenTypeHintBody
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		err := json.NewDecoder(r.Body).Decode(&body)
+		return body, err
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return tokenTypeHintBody{}, err
+	}
+
+	return tokenTypeHintBody{
+		Token:         r.PostFormValue("token"),
+		TokenTypeHint: r.PostFormValue("token_type_hint"),
+		ClientID:      r.PostFormValue("client_id"),
+		ClientSecret:  r.PostFormValue("client_secret"),
+	}, nil
+}
+
+// handleRevoke revokes an access or refresh token as described by RFC 7009. A successful
+// response is returned for unknown tokens so clients cannot probe for valid tokens.
+func (c *AuthController) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	body, err := getTokenTypeHintBody(r)
+	if err != nil {
+		writeOAuthError(w, &auth.Error{Code: auth.InvalidRequest, Description: "malformed request body"})
+		return
+	}
+
+	if body.Token == "" {
+		writeOAuthError(w, &auth.Error{Code: auth.InvalidRequest, Description: "missing token"})
+		return
+	}
+
+	client, err := getClientAuth(r, body.ClientID, body.ClientSecret)
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+
+	err = c.Service.Revoke(r.Context(), body.Token, body.TokenTypeHint, client)
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+type introspectionResponseBody struct {
+	Active    bool   `json:"active"`
+	Subject   string `json:"sub,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+}
+
+// handleIntrospect reports the state of a token as described by RFC 7662.
+func (c *AuthController) handleIntrospect(w http.ResponseWriter, r *http.Request) {
+	body, err := getTokenTypeHintBody(r)
+	if err != nil {
+		writeOAuthError(w, &auth.Error{Code: auth.InvalidRequest, Description: "malformed request body"})
+		return
+	}
+
+	if body.Token == "" {
+		writeOAuthError(w, &auth.Error{Code: auth.InvalidRequest, Description: "missing token"})
+		return
+	}
+
+	client, err := getClientAuth(r, body.ClientID, body.ClientSecret)
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+
+	i, err := c.Service.Introspect(r.Context(), body.Token, body.TokenTypeHint, client)
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+
+	res := introspectionResponseBody{Active: i.Active}
+	if i.Active {
+		res = introspectionResponseBody{
+			Active:    true,
+			Subject:   i.Subject,
+			ClientID:  i.ClientID,
+			Scope:     i.Scope,
+			Issuer:    i.Issuer,
+			TokenType: i.TokenType,
+			IssuedAt:  i.IssuedAt.Unix(),
+			ExpiresAt: i.ExpiresAt.Unix(),
+		}
+	}
+
+	out, err := json.Marshal(res)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(200)
+	w.Write(out)
+}
+
+// handleMetadata serves the server metadata used for both OpenID Connect Discovery and
+// RFC 8414. Endpoint URLs are resolved against the issuer, so the issuer must be the URL
+// the server is reachable at.
+func (c *AuthController) handleMetadata(w http.ResponseWriter, r *http.Request) {
+	issuer := strings.TrimSuffix(c.Service.Issuer(), "/")
+
+	metadata, err := c.Service.Metadata(r.Context(), auth.Endpoints{
+		Authorization: issuer + authorizationPath,
+		Token:         issuer + tokenPath,
+		Revocation:    issuer + revocationPath,
+		Introspection: issuer + introspectionPath,
+		JWKS:          issuer + jwksPath,
+		UserInfo:      issuer + userInfoPath,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	out, err := json.Marshal(metadata)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", jwksMaxAge))
+	w.WriteHeader(200)
+	w.Write(out)
+}
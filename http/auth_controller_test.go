@@ -0,0 +1,172 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/mattmeyers/heimdall/auth"
+	"github.com/mattmeyers/heimdall/store"
+)
+
+func Test_getClientAuth(t *testing.T) {
+	tests := []struct {
+		name         string
+		basicID      string
+		basicSecret  string
+		clientID     string
+		clientSecret string
+		want         auth.ClientAuth
+		wantCode     auth.ErrorCode
+	}{
+		{
+			name:        "HTTP Basic",
+			basicID:     "client",
+			basicSecret: "secret",
+			want:        auth.ClientAuth{ClientID: "client", ClientSecret: "secret", Method: store.ClientSecretBasic},
+		},
+		{
+			name:        "HTTP Basic with a matching client_id",
+			basicID:     "client",
+			basicSecret: "secret",
+			clientID:    "client",
+			want:        auth.ClientAuth{ClientID: "client", ClientSecret: "secret", Method: store.ClientSecretBasic},
+		},
+		{
+			name:        "Form encoded HTTP Basic credentials",
+			basicID:     "my%3Aclient",
+			basicSecret: "p%40ss+w%2Frd",
+			want:        auth.ClientAuth{ClientID: "my:client", ClientSecret: "p@ss w/rd", Method: store.ClientSecretBasic},
+		},
+		{
+			name:        "Malformed HTTP Basic encoding",
+			basicID:     "client",
+			basicSecret: "100%",
+			wantCode:    auth.InvalidClient,
+		},
+		{
+			name:         "HTTP Basic and a secret in the body",
+			basicID:      "client",
+			basicSecret:  "secret",
+			clientSecret: "secret",
+			wantCode:     auth.InvalidRequest,
+		},
+		{
+			name:        "HTTP Basic with a mismatched client_id",
+			basicID:     "client",
+			basicSecret: "secret",
+			clientID:    "other",
+			wantCode:    auth.InvalidRequest,
+		},
+		{
+			name:         "Secret in the body",
+			clientID:     "client",
+			clientSecret: "secret",
+			want:         auth.ClientAuth{ClientID: "client", ClientSecret: "secret", Method: store.ClientSecretPost},
+		},
+		{
+			name:     "Public client sending only client_id",
+			clientID: "client",
+			want:     auth.ClientAuth{ClientID: "client", Method: store.ClientAuthNone},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", tokenPath, nil)
+			if tt.basicID != "" {
+				r.SetBasicAuth(tt.basicID, tt.basicSecret)
+			}
+
+			got, err := getClientAuth(r, tt.clientID, tt.clientSecret)
+
+			var oauthErr *auth.Error
+			if tt.wantCode == "" && err != nil {
+				t.Fatalf("getClientAuth() error = %v", err)
+			} else if tt.wantCode != "" && (!errors.As(err, &oauthErr) || oauthErr.Code != tt.wantCode) {
+				t.Fatalf("getClientAuth() error = %v, want %s", err, tt.wantCode)
+			}
+
+			if got != tt.want {
+				t.Errorf("getClientAuth() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getAuthCodeRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "redirect_uri", query: "redirect_uri=https://example.com/cb", want: "https://example.com/cb"},
+		{name: "redirect_url", query: "redirect_url=https://example.com/cb", want: "https://example.com/cb"},
+		{
+			name:  "redirect_uri takes precedence",
+			query: "redirect_uri=https://example.com/cb&redirect_url=https://example.com/other",
+			want:  "https://example.com/cb",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := getAuthCodeRequest(params).RedirectURL; got != tt.want {
+				t.Errorf("getAuthCodeRequest() redirect URL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_writeInvalidTokenError(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantStatus    int
+		wantChallenge bool
+	}{
+		{name: "Expired", err: auth.ErrTokenExpired, wantStatus: http.StatusUnauthorized, wantChallenge: true},
+		{name: "Revoked", err: auth.ErrTokenRevoked, wantStatus: http.StatusUnauthorized, wantChallenge: true},
+		{
+			name:          "Not an access token",
+			err:           auth.ErrInvalidTokenType,
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: true,
+		},
+		{name: "Unexpected error", err: errors.New("database is closed"), wantStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeInvalidTokenError(rec, tt.err)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+
+			challenge := rec.Header().Get("WWW-Authenticate")
+			if got := strings.Contains(challenge, `error="invalid_token"`); got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate = %q, want invalid_token challenge %v", challenge, tt.wantChallenge)
+			}
+		})
+	}
+}
+
+func Test_writeHTMLPage(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeHTMLPage(rec, []byte("<html></html>"))
+
+	// Sign in and consent pages must not be framed by other sites.
+	if got := rec.Header().Get("X-Frame-Options"); got != "DENY" {
+		t.Errorf("X-Frame-Options = %q, want DENY", got)
+	}
+	if got := rec.Header().Get("Content-Security-Policy"); got != "frame-ancestors 'none'" {
+		t.Errorf("Content-Security-Policy = %q, want frame-ancestors 'none'", got)
+	}
+}
@@ -2,19 +2,35 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/mattmeyers/heimdall/client"
+	"github.com/mattmeyers/heimdall/store"
 )
 
 type ClientController struct {
 	Service client.Service
+	// AdminToken is the bearer token required by the client administration endpoints.
+	// The endpoints are disabled if it is empty.
+	AdminToken string
 }
 
 func (c *ClientController) Register(router *httprouter.Router) {
 	router.HandlerFunc("GET", "/clients/:client_id", c.GetClientByID)
 	router.HandlerFunc("POST", "/clients", c.RegisterClient)
+
+	requireAdmin := newAdminMiddleware(c.AdminToken)
+	router.Handler("GET", "/clients", requireAdmin(http.HandlerFunc(c.ListClients)))
+	router.Handler("PATCH", "/clients/:client_id", requireAdmin(http.HandlerFunc(c.UpdateClient)))
+	router.Handler("DELETE", "/clients/:client_id", requireAdmin(http.HandlerFunc(c.DeleteClient)))
+	router.Handler("POST", "/clients/:client_id/disable", requireAdmin(http.HandlerFunc(c.DisableClient)))
+	router.Handler("POST", "/clients/:client_id/enable", requireAdmin(http.HandlerFunc(c.EnableClient)))
+	router.Handler("POST", "/clients/:client_id/secret", requireAdmin(http.HandlerFunc(c.RotateSecret)))
+	router.Handler("DELETE", "/clients/:client_id/secret/previous", requireAdmin(http.HandlerFunc(c.ExpirePreviousSecret)))
 }
 
 func (c *ClientController) GetClientByID(w http.ResponseWriter, r *http.Request) {
@@ -37,21 +53,172 @@ func (c *ClientController) GetClientByID(w http.ResponseWriter, r *http.Request)
 	w.Write(body)
 }
 
+type listClientsResponse struct {
+	Clients []store.Client `json:"clients"`
+	Total   int            `json:"total"`
+	Offset  int            `json:"offset"`
+	Limit   int            `json:"limit"`
+}
+
+// ListClients returns a page of clients. The page is selected using the offset and limit
+// query parameters.
+func (c *ClientController) ListClients(w http.ResponseWriter, r *http.Request) {
+	offset, err := getIntQueryParam(r, "offset")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit, err := getIntQueryParam(r, "limit")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	clients, total, err := c.Service.List(r.Context(), offset, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if limit == 0 {
+		limit = client.DefaultPageSize
+	}
+
+	body, err := json.Marshal(listClientsResponse{
+		Clients: clients,
+		Total:   total,
+		Offset:  offset,
+		Limit:   limit,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(200)
+	w.Write(body)
+}
+
+// getIntQueryParam parses an optional integer query parameter, returning zero if it is
+// missing.
+func getIntQueryParam(r *http.Request, name string) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, errors.New("invalid " + name)
+	}
+
+	return n, nil
+}
+
 type registerClientBody struct {
-	RedirectURLs []string `json:"redirect_urls"`
+	Name                    string                 `json:"name"`
+	Type                    store.ClientType       `json:"client_type"`
+	RedirectURLs            []string               `json:"redirect_urls"`
+	RequirePKCE             bool                   `json:"require_pkce"`
+	ResourceServer          bool                   `json:"resource_server"`
+	AllowedScopes           []string               `json:"allowed_scopes"`
+	TokenEndpointAuthMethod store.ClientAuthMethod `json:"token_endpoint_auth_method"`
+	GrantTypes              []string               `json:"grant_types"`
+	ResponseTypes           []string               `json:"response_types"`
+	TokenLifespans          store.TokenLifespans   `json:"token_lifespans"`
+}
+
+// registerClientResponse includes the client secret, which is only ever shown once. Public
+// clients do not have a secret.
+type registerClientResponse struct {
+	store.Client
+	ClientSecret string `json:"client_secret,omitempty"`
 }
 
 func (c *ClientController) RegisterClient(w http.ResponseWriter, r *http.Request) {
 	var body registerClientBody
 	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Only an administrator may allow scopes beyond the defaults.
+	register := c.Service.RegisterOpen
+	if hasAdminToken(r, c.AdminToken) {
+		register = c.Service.Register
+	}
+
+	client, secret, err := register(r.Context(), store.Client{
+		Name:                    body.Name,
+		Type:                    body.Type,
+		RedirectURLs:            body.RedirectURLs,
+		RequirePKCE:             body.RequirePKCE,
+		ResourceServer:          body.ResourceServer,
+		AllowedScopes:           body.AllowedScopes,
+		TokenEndpointAuthMethod: body.TokenEndpointAuthMethod,
+		GrantTypes:              body.GrantTypes,
+		ResponseTypes:           body.ResponseTypes,
+		TokenLifespans:          body.TokenLifespans,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resBody, err := json.Marshal(registerClientResponse{Client: client, ClientSecret: secret})
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
 
-	client, err := c.Service.Register(r.Context(), body.RedirectURLs)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(201)
+	w.Write(resBody)
+}
+
+type updateClientBody struct {
+	Name                    *string                 `json:"name"`
+	RedirectURLs            *[]string               `json:"redirect_urls"`
+	RequirePKCE             *bool                   `json:"require_pkce"`
+	ResourceServer          *bool                   `json:"resource_server"`
+	AllowedScopes           *[]string               `json:"allowed_scopes"`
+	TokenEndpointAuthMethod *store.ClientAuthMethod `json:"token_endpoint_auth_method"`
+	GrantTypes              *[]string               `json:"grant_types"`
+	ResponseTypes           *[]string               `json:"response_types"`
+	TokenLifespans          *store.TokenLifespans   `json:"token_lifespans"`
+}
+
+// UpdateClient changes the fields present in the request body.
+func (c *ClientController) UpdateClient(w http.ResponseWriter, r *http.Request) {
+	clientID := httprouter.ParamsFromContext(r.Context()).ByName("client_id")
+
+	var body updateClientBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := c.Service.Get(r.Context(), clientID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	client, err := c.Service.Update(r.Context(), clientID, client.ClientUpdate{
+		Name:                    body.Name,
+		RedirectURLs:            body.RedirectURLs,
+		RequirePKCE:             body.RequirePKCE,
+		ResourceServer:          body.ResourceServer,
+		AllowedScopes:           body.AllowedScopes,
+		TokenEndpointAuthMethod: body.TokenEndpointAuthMethod,
+		GrantTypes:              body.GrantTypes,
+		ResponseTypes:           body.ResponseTypes,
+		TokenLifespans:          body.TokenLifespans,
+	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -62,6 +229,85 @@ func (c *ClientController) RegisterClient(w http.ResponseWriter, r *http.Request
 	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(201)
+	w.WriteHeader(200)
 	w.Write(resBody)
 }
+
+func (c *ClientController) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	clientID := httprouter.ParamsFromContext(r.Context()).ByName("client_id")
+
+	if err := c.Service.Delete(r.Context(), clientID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *ClientController) DisableClient(w http.ResponseWriter, r *http.Request) {
+	clientID := httprouter.ParamsFromContext(r.Context()).ByName("client_id")
+
+	if err := c.Service.Disable(r.Context(), clientID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *ClientController) EnableClient(w http.ResponseWriter, r *http.Request) {
+	clientID := httprouter.ParamsFromContext(r.Context()).ByName("client_id")
+
+	if err := c.Service.Enable(r.Context(), clientID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type rotateSecretResponse struct {
+	ClientID                string    `json:"client_id"`
+	ClientSecret            string    `json:"client_secret"`
+	PreviousSecretExpiresAt time.Time `json:"previous_secret_expires_at"`
+}
+
+// RotateSecret issues a new secret for the client. The previous secret keeps working until
+// previous_secret_expires_at.
+func (c *ClientController) RotateSecret(w http.ResponseWriter, r *http.Request) {
+	clientID := httprouter.ParamsFromContext(r.Context()).ByName("client_id")
+
+	secret, expiresAt, err := c.Service.RotateSecret(r.Context(), clientID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	body, err := json.Marshal(rotateSecretResponse{
+		ClientID:                clientID,
+		ClientSecret:            secret,
+		PreviousSecretExpiresAt: expiresAt.UTC(),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(200)
+	w.Write(body)
+}
+
+// ExpirePreviousSecret stops accepting the client's previous secret before its grace period
+// ends.
+func (c *ClientController) ExpirePreviousSecret(w http.ResponseWriter, r *http.Request) {
+	clientID := httprouter.ParamsFromContext(r.Context()).ByName("client_id")
+
+	if err := c.Service.ExpirePreviousSecret(r.Context(), clientID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,250 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mattmeyers/heimdall/client"
+	"github.com/mattmeyers/heimdall/store"
+)
+
+// clientStoreStub knows of no clients, so any request for an existing client fails with a
+// not found error. Created clients are recorded if created is set, but are not stored.
+type clientStoreStub struct {
+	store.ClientStore
+	created *[]store.Client
+}
+
+func (s clientStoreStub) Create(ctx context.Context, c store.Client) (int, error) {
+	if s.created != nil {
+		*s.created = append(*s.created, c)
+	}
+	return 1, nil
+}
+
+func (s clientStoreStub) GetByClientID(ctx context.Context, id string) (store.Client, error) {
+	return store.Client{}, errors.New("client not found")
+}
+
+func (s clientStoreStub) Delete(ctx context.Context, id string) error {
+	return errors.New("client not found")
+}
+
+func (s clientStoreStub) SetDisabled(ctx context.Context, id string, disabled bool) error {
+	return errors.New("client not found")
+}
+
+func (s clientStoreStub) ExpirePreviousSecret(ctx context.Context, id string) error {
+	return errors.New("client not found")
+}
+
+var testMaxLifespans = store.TokenLifespans{AccessToken: 3600, RefreshToken: 3600, IDToken: 3600, AuthCode: 600}
+
+type scopeStoreStub []store.Scope
+
+func (s scopeStoreStub) List(ctx context.Context) ([]store.Scope, error) { return s, nil }
+
+func (s scopeStoreStub) Create(ctx context.Context, sc store.Scope) (int, error) { return 1, nil }
+
+func TestClientController_adminRoutes(t *testing.T) {
+	service, err := client.NewService(clientStoreStub{}, nil, time.Hour, testMaxLifespans)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := []struct {
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{method: "GET", path: "/clients?limit=-1", wantStatus: http.StatusBadRequest},
+		{method: "PATCH", path: "/clients/abc", body: "{}", wantStatus: http.StatusNotFound},
+		{method: "DELETE", path: "/clients/abc", wantStatus: http.StatusNotFound},
+		{method: "POST", path: "/clients/abc/disable", wantStatus: http.StatusNotFound},
+		{method: "POST", path: "/clients/abc/enable", wantStatus: http.StatusNotFound},
+		{method: "POST", path: "/clients/abc/secret", wantStatus: http.StatusNotFound},
+		{method: "DELETE", path: "/clients/abc/secret/previous", wantStatus: http.StatusNotFound},
+	}
+
+	tests := []struct {
+		name          string
+		adminToken    string
+		authorization string
+		wantStatus    int
+	}{
+		{
+			name:          "Missing token",
+			adminToken:    "admin",
+			authorization: "",
+			wantStatus:    http.StatusUnauthorized,
+		},
+		{
+			name:          "Wrong token",
+			adminToken:    "admin",
+			authorization: "Bearer nope",
+			wantStatus:    http.StatusUnauthorized,
+		},
+		{
+			name:          "Admin API disabled",
+			adminToken:    "",
+			authorization: "Bearer ",
+			wantStatus:    http.StatusForbidden,
+		},
+		{
+			name:          "Admin token",
+			adminToken:    "admin",
+			authorization: "Bearer admin",
+		},
+	}
+	for _, tt := range tests {
+		router := httprouter.New()
+		c := &ClientController{Service: *service, AdminToken: tt.adminToken}
+		c.Register(router)
+
+		for _, route := range routes {
+			t.Run(tt.name+" "+route.method+" "+route.path, func(t *testing.T) {
+				req := httptest.NewRequest(route.method, route.path, strings.NewReader(route.body))
+				if tt.authorization != "" {
+					req.Header.Set("Authorization", tt.authorization)
+				}
+
+				rec := httptest.NewRecorder()
+				router.ServeHTTP(rec, req)
+
+				// Requests that pass the admin check are handled by the route.
+				wantStatus := tt.wantStatus
+				if wantStatus == 0 {
+					wantStatus = route.wantStatus
+				}
+				if rec.Code != wantStatus {
+					t.Errorf("status = %d, want %d", rec.Code, wantStatus)
+				}
+			})
+		}
+	}
+}
+
+func TestClientController_RegisterClient(t *testing.T) {
+	scopes := scopeStoreStub{{Name: "openid"}, {Name: "admin"}}
+	service, err := client.NewService(clientStoreStub{}, scopes, time.Hour, testMaxLifespans)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		body          string
+		authorization string
+		wantStatus    int
+	}{
+		{name: "Malformed body", body: "{", wantStatus: http.StatusBadRequest},
+		{
+			name:       "Invalid redirect URL",
+			body:       `{"redirect_urls":["https://example.com/cb#fragment"]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Default scope",
+			body:       `{"allowed_scopes":["openid"]}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "Privileged scope without admin token",
+			body:       `{"allowed_scopes":["openid","admin"]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:          "Privileged scope with wrong token",
+			body:          `{"allowed_scopes":["openid","admin"]}`,
+			authorization: "Bearer nope",
+			wantStatus:    http.StatusBadRequest,
+		},
+		{
+			name:          "Privileged scope with admin token",
+			body:          `{"allowed_scopes":["openid","admin"]}`,
+			authorization: "Bearer admin",
+			wantStatus:    http.StatusCreated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := httprouter.New()
+			c := &ClientController{Service: *service, AdminToken: "admin"}
+			c.Register(router)
+
+			req := httptest.NewRequest("POST", "/clients", strings.NewReader(tt.body))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// TestClientController_RegisterClient_clientCredentials ensures that an anonymous caller
+// cannot register a client that could obtain privileged tokens with the client_credentials
+// grant.
+func TestClientController_RegisterClient_clientCredentials(t *testing.T) {
+	scopes := scopeStoreStub{{Name: "admin"}}
+	body := `{"grant_types":["client_credentials"],"allowed_scopes":["admin"]}`
+
+	tests := []struct {
+		name          string
+		body          string
+		authorization string
+		wantStatus    int
+	}{
+		{name: "Anonymous", body: body, wantStatus: http.StatusBadRequest},
+		{
+			name:       "Anonymous without scopes",
+			body:       `{"grant_types":["client_credentials"]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{name: "Admin", body: body, authorization: "Bearer admin", wantStatus: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created []store.Client
+			service, err := client.NewService(clientStoreStub{created: &created}, scopes, time.Hour, testMaxLifespans)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			router := httprouter.New()
+			c := &ClientController{Service: *service, AdminToken: "admin"}
+			c.Register(router)
+
+			req := httptest.NewRequest("POST", "/clients", strings.NewReader(tt.body))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+
+			// A rejected registration must not leave behind a client whose secret could be
+			// used at the token endpoint.
+			wantCreated := tt.wantStatus == http.StatusCreated
+			if (len(created) > 0) != wantCreated || strings.Contains(rec.Body.String(), "client_secret") != wantCreated {
+				t.Errorf("client created = %v, want %v", len(created) > 0, wantCreated)
+			}
+		})
+	}
+}
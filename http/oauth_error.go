@@ -0,0 +1,77 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/mattmeyers/heimdall/auth"
+)
+
+type errorResponseBody struct {
+	Error            auth.ErrorCode `json:"error"`
+	ErrorDescription string         `json:"error_description,omitempty"`
+	ErrorURI         string         `json:"error_uri,omitempty"`
+}
+
+// asOAuthError converts err into an OAuth error. Errors that are not meant for the client
+// are reported as a server_error without exposing their details.
+func asOAuthError(err error) *auth.Error {
+	var oauthErr *auth.Error
+	if errors.As(err, &oauthErr) {
+		return oauthErr
+	}
+
+	return &auth.Error{Code: auth.ServerError, Description: "the server encountered an unexpected error"}
+}
+
+// writeOAuthError writes an error response from the token, revocation or introspection
+// endpoint as described by RFC 6749 section 5.2. Client authentication failures receive a
+// 401 with a WWW-Authenticate challenge, and all other client errors a 400.
+func writeOAuthError(w http.ResponseWriter, err error) {
+	oauthErr := asOAuthError(err)
+
+	status := http.StatusBadRequest
+	switch oauthErr.Code {
+	case auth.InvalidClient:
+		status = http.StatusUnauthorized
+		w.Header().Set("WWW-Authenticate", `Basic realm="heimdall"`)
+	case auth.ServerError:
+		status = http.StatusInternalServerError
+	}
+
+	out, err := json.Marshal(errorResponseBody{
+		Error:            oauthErr.Code,
+		ErrorDescription: oauthErr.Description,
+		ErrorURI:         oauthErr.URI,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(status)
+	w.Write(out)
+}
+
+// writeAuthorizationError reports an error from the authorization endpoint. If the client
+// and redirect URL could not be verified, the error is shown to the user. Otherwise the user
+// agent is redirected back to the client with the error and state (RFC 6749 section
+// 4.1.2.1).
+func writeAuthorizationError(w http.ResponseWriter, r *http.Request, req auth.AuthCodeRequest, err error) {
+	if errors.Is(err, auth.ErrUnknownClient) || errors.Is(err, auth.ErrInvalidRedirectURL) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	redirect, err := generateAuthErrorRedirect(req.RedirectURL, asOAuthError(err), req.State)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
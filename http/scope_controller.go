@@ -0,0 +1,72 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mattmeyers/heimdall/scope"
+)
+
+type ScopeController struct {
+	Service scope.Service
+	// AdminToken is the bearer token required to register scopes. Scopes cannot be
+	// registered if it is empty.
+	AdminToken string
+}
+
+func (c *ScopeController) Register(router *httprouter.Router) {
+	router.HandlerFunc("GET", "/scopes", c.ListScopes)
+
+	requireAdmin := newAdminMiddleware(c.AdminToken)
+	router.Handler("POST", "/scopes", requireAdmin(http.HandlerFunc(c.RegisterScope)))
+}
+
+func (c *ScopeController) ListScopes(w http.ResponseWriter, r *http.Request) {
+	scopes, err := c.Service.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(scopes)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(200)
+	w.Write(body)
+}
+
+type registerScopeBody struct {
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	RequiresConsent bool   `json:"requires_consent"`
+}
+
+func (c *ScopeController) RegisterScope(w http.ResponseWriter, r *http.Request) {
+	var body registerScopeBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sc, err := c.Service.Register(r.Context(), body.Name, body.Description, body.RequiresConsent)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resBody, err := json.Marshal(sc)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(201)
+	w.Write(resBody)
+}
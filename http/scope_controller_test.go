@@ -0,0 +1,49 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mattmeyers/heimdall/scope"
+)
+
+func TestScopeController_RegisterScope(t *testing.T) {
+	service, err := scope.NewService(scopeStoreStub{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		adminToken    string
+		authorization string
+		wantStatus    int
+	}{
+		{name: "Missing token", adminToken: "admin", authorization: "", wantStatus: http.StatusUnauthorized},
+		{name: "Wrong token", adminToken: "admin", authorization: "Bearer nope", wantStatus: http.StatusUnauthorized},
+		{name: "Admin API disabled", adminToken: "", authorization: "Bearer ", wantStatus: http.StatusForbidden},
+		{name: "Admin token", adminToken: "admin", authorization: "Bearer admin", wantStatus: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := httprouter.New()
+			c := &ScopeController{Service: *service, AdminToken: tt.adminToken}
+			c.Register(router)
+
+			req := httptest.NewRequest("POST", "/scopes", strings.NewReader(`{"name":"admin"}`))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
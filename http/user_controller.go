@@ -22,6 +22,7 @@ func (c *UserController) Register(router *httprouter.Router) {
 type registrationBody struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Name     string `json:"name"`
 }
 
 func (c *UserController) RegisterUser(w http.ResponseWriter, r *http.Request) {
@@ -32,7 +33,7 @@ func (c *UserController) RegisterUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := c.Service.Register(r.Context(), body.Email, body.Password)
+	id, err := c.Service.Register(r.Context(), body.Email, body.Password, body.Name)
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
@@ -0,0 +1,56 @@
+package scope
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mattmeyers/heimdall/store"
+)
+
+type Service struct {
+	scopeStore store.ScopeStore
+}
+
+func NewService(s store.ScopeStore) (*Service, error) {
+	return &Service{scopeStore: s}, nil
+}
+
+func (s *Service) List(ctx context.Context) ([]store.Scope, error) {
+	return s.scopeStore.List(ctx)
+}
+
+func (s *Service) Register(ctx context.Context, name, description string, requiresConsent bool) (store.Scope, error) {
+	if err := validateName(name); err != nil {
+		return store.Scope{}, err
+	}
+
+	sc := store.Scope{
+		Name:            name,
+		Description:     description,
+		RequiresConsent: requiresConsent,
+	}
+
+	var err error
+	if sc.ID, err = s.scopeStore.Create(ctx, sc); err != nil {
+		return store.Scope{}, err
+	}
+
+	return sc, nil
+}
+
+// validateName ensures the name is a valid scope-token as defined by section 3.3 of
+// RFC 6749. Spaces, double quotes, and backslashes are not allowed.
+func validateName(name string) error {
+	if name == "" {
+		return errors.New("scope name is required")
+	}
+
+	for _, c := range name {
+		if c < 0x21 || c > 0x7e || c == '"' || c == '\\' {
+			return fmt.Errorf("invalid character in scope name: %q", c)
+		}
+	}
+
+	return nil
+}
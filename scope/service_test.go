@@ -0,0 +1,26 @@
+package scope
+
+import "testing"
+
+func Test_validateName(t *testing.T) {
+	tests := []struct {
+		name    string
+		scope   string
+		wantErr bool
+	}{
+		{name: "Simple", scope: "read", wantErr: false},
+		{name: "Punctuation", scope: "https://api.example.com/read:all", wantErr: false},
+		{name: "Empty", scope: "", wantErr: true},
+		{name: "Space", scope: "read write", wantErr: true},
+		{name: "Double quote", scope: `read"`, wantErr: true},
+		{name: "Backslash", scope: `read\`, wantErr: true},
+		{name: "Non-ASCII", scope: "lecture·écriture", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateName(tt.scope); (err != nil) != tt.wantErr {
+				t.Errorf("validateName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
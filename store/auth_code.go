@@ -2,17 +2,40 @@ package store
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrAuthCodeUsed is returned when attempting to consume an auth code that has already
+// been exchanged for tokens.
+var ErrAuthCodeUsed = errors.New("auth code already used")
+
 type AuthCode struct {
-	ID        int
-	Code      string
-	UserID    int
+	ID                  int
+	Code                string
+	UserID              int
+	ClientID            string
+	RedirectURL         string
+	Scope               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	// AuthTime is the time the user signed in to authorize the request.
+	AuthTime  time.Time
 	CreatedAt time.Time
+	Consumed  bool
+	// TokenFamilyID identifies the refresh token family issued when the code was consumed.
+	TokenFamilyID string
+	// AccessTokenID and AccessTokenExpiresAt identify the access token issued when the code
+	// was consumed, so that it can be revoked if the code is replayed.
+	AccessTokenID        string
+	AccessTokenExpiresAt time.Time
 }
 
 type AuthCodeStore interface {
 	GetByCode(ctx context.Context, code string) (AuthCode, error)
 	Insert(ctx context.Context, code AuthCode) (int, error)
+	// Consume marks the code as used and records the refresh token family and access token
+	// issued for it. If the code has already been consumed, ErrAuthCodeUsed is returned.
+	Consume(ctx context.Context, id int, tokenFamilyID, accessTokenID string, accessTokenExpiresAt time.Time) error
 }
@@ -1,15 +1,86 @@
 package store
 
-import "context"
+import (
+	"context"
+	"time"
+)
+
+// ClientAuthMethod is how a client authenticates at the token endpoint (RFC 7591 section 2).
+type ClientAuthMethod string
+
+const (
+	// ClientSecretBasic clients send their credentials using HTTP Basic authentication.
+	ClientSecretBasic ClientAuthMethod = "client_secret_basic"
+	// ClientSecretPost clients send their credentials in the request body.
+	ClientSecretPost ClientAuthMethod = "client_secret_post"
+	// ClientAuthNone is used by public clients, which only identify themselves with their
+	// client_id.
+	ClientAuthNone ClientAuthMethod = "none"
+)
+
+// ClientType is whether a client can keep its credentials confidential (RFC 6749 section 2.1).
+type ClientType string
+
+const (
+	// ConfidentialClient clients, such as server side applications, authenticate using a
+	// secret.
+	ConfidentialClient ClientType = "confidential"
+	// PublicClient clients, such as single page and native applications, cannot keep a
+	// secret. They have no secret and must use PKCE.
+	PublicClient ClientType = "public"
+)
 
 type Client struct {
-	ID           int      `json:"id"`
-	ClientID     string   `json:"client_id"`
-	ClientSecret string   `json:"client_secret"`
-	RedirectURLs []string `json:"redirect_urls"`
+	ID            int        `json:"id"`
+	ClientID      string     `json:"client_id"`
+	Name          string     `json:"name"`
+	Type          ClientType `json:"client_type"`
+	SecretHash    string     `json:"-"`
+	RedirectURLs  []string   `json:"redirect_urls"`
+	RequirePKCE   bool       `json:"require_pkce"`
+	AllowedScopes []string   `json:"allowed_scopes"`
+	// GrantTypes and ResponseTypes are the flows the client may use (RFC 7591 section 2).
+	GrantTypes    []string `json:"grant_types"`
+	ResponseTypes []string `json:"response_types"`
+	// Disabled clients cannot start an authorization or authenticate at the token endpoint.
+	Disabled bool `json:"disabled"`
+	// ResourceServer clients may introspect access tokens issued to other clients. Only an
+	// administrator can register a resource server.
+	ResourceServer bool `json:"resource_server"`
+	// TokenEndpointAuthMethod is the only method the client may use to authenticate.
+	TokenEndpointAuthMethod ClientAuthMethod `json:"token_endpoint_auth_method"`
+	TokenLifespans          TokenLifespans   `json:"token_lifespans"`
+	// PreviousSecretHash is the hash of the secret replaced by the last rotation. It remains
+	// valid until PreviousSecretExpiresAt so that the client can be updated without downtime.
+	PreviousSecretHash      string    `json:"-"`
+	PreviousSecretExpiresAt time.Time `json:"-"`
+}
+
+// TokenLifespans override the server's default lifespans for tokens issued to a client. Each
+// value is a number of seconds, and zero uses the server default.
+type TokenLifespans struct {
+	AccessToken  int `json:"access_token"`
+	RefreshToken int `json:"refresh_token"`
+	IDToken      int `json:"id_token"`
+	AuthCode     int `json:"auth_code"`
 }
 
 type ClientStore interface {
 	GetByClientID(ctx context.Context, id string) (Client, error)
+	// List returns a page of clients ordered by ID along with the total number of clients.
+	List(ctx context.Context, offset, limit int) ([]Client, int, error)
 	Create(ctx context.Context, c Client) (int, error)
+	// Update replaces the client's settings, redirect URLs and allowed scopes. Secrets are
+	// changed using RotateSecret.
+	Update(ctx context.Context, c Client) error
+	SetDisabled(ctx context.Context, clientID string, disabled bool) error
+	// Delete removes the client along with its auth codes, refresh tokens and consents.
+	Delete(ctx context.Context, clientID string) error
+	// ListSecretHashes returns the secret hash of every client keyed by client_id.
+	ListSecretHashes(ctx context.Context) (map[string]string, error)
+	UpdateSecretHash(ctx context.Context, clientID, secretHash string) error
+	// RotateSecret replaces the client's secret hash, keeping the current one as the previous
+	// secret until previousExpiresAt.
+	RotateSecret(ctx context.Context, clientID, secretHash string, previousExpiresAt time.Time) error
+	ExpirePreviousSecret(ctx context.Context, clientID string) error
 }
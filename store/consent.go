@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// ConsentGrant records the scopes a user has agreed to grant a client.
+type ConsentGrant struct {
+	UserID    int       `json:"-"`
+	ClientID  string    `json:"client_id"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ConsentRequest is an authorization request from a signed in user that is waiting on the
+// user's consent. It is identified by an unguessable challenge embedded in the consent
+// screen.
+type ConsentRequest struct {
+	ID                  int
+	Challenge           string
+	UserID              int
+	ClientID            string
+	RedirectURL         string
+	State               string
+	Scope               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	// AuthTime is the time the user signed in before being shown the consent screen.
+	AuthTime  time.Time
+	CreatedAt time.Time
+}
+
+type ConsentStore interface {
+	// GetGrant returns the scopes the user has granted the client. If the user has not
+	// granted the client anything, a grant with no scopes is returned.
+	GetGrant(ctx context.Context, userID int, clientID string) (ConsentGrant, error)
+	ListGrants(ctx context.Context, userID int) ([]ConsentGrant, error)
+	// Grant adds the scopes to the user's existing grant for the client.
+	Grant(ctx context.Context, g ConsentGrant) error
+	RevokeGrant(ctx context.Context, userID int, clientID string) error
+
+	InsertRequest(ctx context.Context, r ConsentRequest) (int, error)
+	// ConsumeRequest returns and deletes the request with the challenge so that it can only
+	// be answered once.
+	ConsumeRequest(ctx context.Context, challenge string) (ConsentRequest, error)
+	// DeleteExpiredRequests removes every request created before the provided time.
+	DeleteExpiredRequests(ctx context.Context, before time.Time) error
+}
@@ -0,0 +1,195 @@
+// Package file provides stores that persist to a directory on the local filesystem.
+package file
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattmeyers/heimdall/store"
+)
+
+var _ store.SigningKeyStore = (*SigningKeyStore)(nil)
+
+// The PEM headers used to store key metadata alongside the key.
+const (
+	stateHeader       = "State"
+	createdAtHeader   = "Created-At"
+	activatedAtHeader = "Activated-At"
+	retiredAtHeader   = "Retired-At"
+)
+
+// SigningKeyStore stores each signing key as a PEM file in a directory. The file is named
+// after the key ID, and the key's metadata is stored in the PEM headers.
+type SigningKeyStore struct {
+	dir string
+}
+
+func NewSigningKeyStore(dir string) (*SigningKeyStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &SigningKeyStore{dir: dir}, nil
+}
+
+func (s *SigningKeyStore) List(ctx context.Context) ([]store.SigningKey, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []store.SigningKey
+	for _, path := range paths {
+		k, err := readKeyFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+
+	return keys, nil
+}
+
+func (s *SigningKeyStore) Insert(ctx context.Context, k store.SigningKey) error {
+	path, err := s.path(k.ID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return errors.New("signing key already exists")
+	}
+
+	return writeKeyFile(path, k)
+}
+
+func (s *SigningKeyStore) Update(ctx context.Context, k store.SigningKey) error {
+	path, err := s.path(k.ID)
+	if err != nil {
+		return err
+	}
+
+	existing, err := readKeyFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return errors.New("signing key not found")
+	} else if err != nil {
+		return err
+	}
+
+	existing.State = k.State
+	existing.ActivatedAt = k.ActivatedAt
+	existing.RetiredAt = k.RetiredAt
+
+	return writeKeyFile(path, existing)
+}
+
+func (s *SigningKeyStore) Delete(ctx context.Context, id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *SigningKeyStore) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return "", errors.New("invalid signing key ID")
+	}
+
+	return filepath.Join(s.dir, id+".pem"), nil
+}
+
+func readKeyFile(path string) (store.SigningKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return store.SigningKey{}, err
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return store.SigningKey{}, errors.New("key must be PEM encoded")
+	}
+
+	k := store.SigningKey{
+		ID:    strings.TrimSuffix(filepath.Base(path), ".pem"),
+		State: store.SigningKeyState(block.Headers[stateHeader]),
+	}
+
+	if k.CreatedAt, err = time.Parse(time.RFC3339, block.Headers[createdAtHeader]); err != nil {
+		return store.SigningKey{}, err
+	}
+
+	if k.ActivatedAt, err = parseOptionalTime(block.Headers[activatedAtHeader]); err != nil {
+		return store.SigningKey{}, err
+	}
+
+	if k.RetiredAt, err = parseOptionalTime(block.Headers[retiredAtHeader]); err != nil {
+		return store.SigningKey{}, err
+	}
+
+	block.Headers = nil
+	k.PrivateKey = pem.EncodeToMemory(block)
+
+	return k, nil
+}
+
+// writeKeyFile writes the key to a temporary file before moving it into place so that a
+// partially written key is never read.
+func writeKeyFile(path string, k store.SigningKey) error {
+	block, _ := pem.Decode(k.PrivateKey)
+	if block == nil {
+		return errors.New("key must be PEM encoded")
+	}
+
+	block.Headers = map[string]string{
+		stateHeader:     string(k.State),
+		createdAtHeader: k.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	if !k.ActivatedAt.IsZero() {
+		block.Headers[activatedAtHeader] = k.ActivatedAt.UTC().Format(time.RFC3339)
+	}
+	if !k.RetiredAt.IsZero() {
+		block.Headers[retiredAtHeader] = k.RetiredAt.UTC().Format(time.RFC3339)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".key-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := pem.Encode(tmp, block); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// parseOptionalTime parses an RFC 3339 timestamp. An empty string is the zero time.
+func parseOptionalTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrRefreshTokenUsed is returned when attempting to use a refresh token that has already
+// been exchanged for a new token pair.
+var ErrRefreshTokenUsed = errors.New("refresh token already used")
+
+// RefreshToken is an opaque token that can be exchanged for a new access token. Every token
+// belongs to a family that is shared by all tokens rotated from the same original grant.
+type RefreshToken struct {
+	ID       int
+	Token    string
+	FamilyID string
+	UserID   int
+	ClientID string
+	Scope    string
+	// AuthTime is the time the user authenticated to start the token family.
+	AuthTime  time.Time
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	Used      bool
+	Revoked   bool
+}
+
+type RefreshTokenStore interface {
+	GetByToken(ctx context.Context, token string) (RefreshToken, error)
+	Insert(ctx context.Context, t RefreshToken) (int, error)
+	// MarkUsed flags the token as used. If the token has already been used,
+	// ErrRefreshTokenUsed is returned.
+	MarkUsed(ctx context.Context, id int) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	// RevokeClient revokes every refresh token issued to the client on behalf of the user.
+	RevokeClient(ctx context.Context, userID int, clientID string) error
+}
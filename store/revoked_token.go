@@ -0,0 +1,21 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// RevokedToken records the ID of an access token that was revoked before it expired. The
+// record only needs to be kept until the token would have expired on its own.
+type RevokedToken struct {
+	TokenID   string
+	RevokedAt time.Time
+	ExpiresAt time.Time
+}
+
+type RevokedTokenStore interface {
+	Insert(ctx context.Context, t RevokedToken) error
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+	// DeleteExpired removes every record whose token expired before the provided time.
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
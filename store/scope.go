@@ -0,0 +1,17 @@
+package store
+
+import "context"
+
+// Scope is a registered scope that clients can be allowed to request.
+type Scope struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// RequiresConsent determines if the user must approve the scope before it is granted.
+	RequiresConsent bool `json:"requires_consent"`
+}
+
+type ScopeStore interface {
+	List(ctx context.Context) ([]Scope, error)
+	Create(ctx context.Context, s Scope) (int, error)
+}
@@ -0,0 +1,37 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+type SigningKeyState string
+
+// The lifecycle states of a signing key. A next key is published for verification before it
+// signs anything, the active key signs new tokens, and retired keys only verify tokens that
+// were issued before the last rotation.
+const (
+	SigningKeyNext    SigningKeyState = "next"
+	SigningKeyActive  SigningKeyState = "active"
+	SigningKeyRetired SigningKeyState = "retired"
+)
+
+type SigningKey struct {
+	// ID is the key ID placed in the kid header of tokens signed by this key.
+	ID    string
+	State SigningKeyState
+	// PrivateKey is the PEM encoded private key.
+	PrivateKey []byte
+	CreatedAt  time.Time
+	// ActivatedAt is the time the key became active, or the zero time if it never has.
+	ActivatedAt time.Time
+	// RetiredAt is the time the key was retired, or the zero time if it is not retired.
+	RetiredAt time.Time
+}
+
+type SigningKeyStore interface {
+	List(ctx context.Context) ([]SigningKey, error)
+	Insert(ctx context.Context, k SigningKey) error
+	Update(ctx context.Context, k SigningKey) error
+	Delete(ctx context.Context, id string) error
+}
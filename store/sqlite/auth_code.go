@@ -4,10 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/mattmeyers/heimdall/store"
 )
 
+var _ store.AuthCodeStore = (*AuthCodeStore)(nil)
+
 type AuthCodeStore struct {
 	db *sql.DB
 }
@@ -18,17 +21,39 @@ func NewAuthCodeStore(db *sql.DB) (*AuthCodeStore, error) {
 
 func (s *AuthCodeStore) GetByCode(ctx context.Context, code string) (store.AuthCode, error) {
 	var c store.AuthCode
+	var accessTokenExpiresAt sql.NullTime
 	err := s.db.
 		QueryRowContext(
 			ctx,
-			`SELECT id, user_id, code, created_at FROM auth_code WHERE code = ?`,
+			`SELECT id, user_id, client_id, redirect_url, scope, nonce, code, code_challenge,
+				code_challenge_method, auth_time, created_at, consumed_at IS NOT NULL, token_family_id,
+				access_token_id, access_token_expires_at
+			FROM auth_code WHERE code = ?`,
 			code,
 		).
-		Scan(&c.ID, &c.UserID, &c.Code, &c.CreatedAt)
+		Scan(
+			&c.ID,
+			&c.UserID,
+			&c.ClientID,
+			&c.RedirectURL,
+			&c.Scope,
+			&c.Nonce,
+			&c.Code,
+			&c.CodeChallenge,
+			&c.CodeChallengeMethod,
+			&c.AuthTime,
+			&c.CreatedAt,
+			&c.Consumed,
+			&c.TokenFamilyID,
+			&c.AccessTokenID,
+			&accessTokenExpiresAt,
+		)
 	if err != nil {
 		return store.AuthCode{}, errors.New("auth code not found")
 	}
 
+	c.AccessTokenExpiresAt = accessTokenExpiresAt.Time
+
 	return c, nil
 }
 
@@ -40,9 +65,19 @@ func (s *AuthCodeStore) Insert(ctx context.Context, code store.AuthCode) (int, e
 	defer tx.Commit()
 
 	res, err := tx.Exec(
-		`INSERT INTO auth_code (user_id, code, created_at) VALUES (?, ?, NOW())`,
+		`INSERT INTO auth_code (user_id, client_id, redirect_url, scope, nonce, code,
+			code_challenge, code_challenge_method, auth_time, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		code.UserID,
+		code.ClientID,
+		code.RedirectURL,
+		code.Scope,
+		code.Nonce,
 		code.Code,
+		code.CodeChallenge,
+		code.CodeChallengeMethod,
+		code.AuthTime.UTC(),
+		code.CreatedAt.UTC(),
 	)
 	if err != nil {
 		tx.Rollback()
@@ -57,3 +92,29 @@ func (s *AuthCodeStore) Insert(ctx context.Context, code store.AuthCode) (int, e
 
 	return int(id), nil
 }
+
+func (s *AuthCodeStore) Consume(ctx context.Context, id int, tokenFamilyID, accessTokenID string, accessTokenExpiresAt time.Time) error {
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE auth_code SET consumed_at = ?, token_family_id = ?, access_token_id = ?,
+			access_token_expires_at = ?
+		WHERE id = ? AND consumed_at IS NULL`,
+		time.Now().UTC(),
+		tokenFamilyID,
+		accessTokenID,
+		accessTokenExpiresAt.UTC(),
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	} else if n == 0 {
+		return store.ErrAuthCodeUsed
+	}
+
+	return nil
+}
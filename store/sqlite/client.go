@@ -4,10 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/mattmeyers/heimdall/store"
 )
 
+var _ store.ClientStore = (*ClientStore)(nil)
+
 type ClientStore struct {
 	db *sql.DB
 }
@@ -16,42 +20,174 @@ func NewClientStore(db *sql.DB) (*ClientStore, error) {
 	return &ClientStore{db: db}, nil
 }
 
-func (s *ClientStore) GetByClientID(ctx context.Context, clientID string) (store.Client, error) {
+// clientColumns are the client columns read by scanClient.
+const clientColumns = `id, client_id, name, client_type, secret_hash, require_pkce, disabled,
+	resource_server, token_endpoint_auth_method, grant_types, response_types, access_token_lifespan,
+	refresh_token_lifespan, id_token_lifespan, auth_code_lifespan, previous_secret_hash,
+	previous_secret_expires_at`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanClient(row scanner) (store.Client, error) {
 	var c store.Client
-	err := s.db.
-		QueryRowContext(
-			ctx,
-			`SELECT id, client_id, client_secret FROM client WHERE client_id = ?`,
-			clientID,
-		).
-		Scan(&c.ID, &c.ClientID, &c.ClientSecret)
+	var grantTypes, responseTypes string
+	var previousHash sql.NullString
+	var previousExpiresAt sql.NullTime
+	err := row.Scan(
+		&c.ID,
+		&c.ClientID,
+		&c.Name,
+		&c.Type,
+		&c.SecretHash,
+		&c.RequirePKCE,
+		&c.Disabled,
+		&c.ResourceServer,
+		&c.TokenEndpointAuthMethod,
+		&grantTypes,
+		&responseTypes,
+		&c.TokenLifespans.AccessToken,
+		&c.TokenLifespans.RefreshToken,
+		&c.TokenLifespans.IDToken,
+		&c.TokenLifespans.AuthCode,
+		&previousHash,
+		&previousExpiresAt,
+	)
+	if err != nil {
+		return store.Client{}, err
+	}
+	c.GrantTypes = strings.Fields(grantTypes)
+	c.ResponseTypes = strings.Fields(responseTypes)
+	c.PreviousSecretHash = previousHash.String
+	c.PreviousSecretExpiresAt = previousExpiresAt.Time
+
+	return c, nil
+}
+
+func (s *ClientStore) GetByClientID(ctx context.Context, clientID string) (store.Client, error) {
+	c, err := scanClient(s.db.QueryRowContext(
+		ctx,
+		`SELECT `+clientColumns+` FROM client WHERE client_id = ?`,
+		clientID,
+	))
 	if err != nil {
 		return store.Client{}, errors.New("client not found")
 	}
 
+	if err = s.loadAssociations(ctx, &c); err != nil {
+		return store.Client{}, err
+	}
+
+	return c, nil
+}
+
+func (s *ClientStore) List(ctx context.Context, offset, limit int) ([]store.Client, int, error) {
+	var total int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM client`).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT `+clientColumns+` FROM client ORDER BY id LIMIT ? OFFSET ?`,
+		limit,
+		offset,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var clients []store.Client
+	for rows.Next() {
+		c, err := scanClient(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		clients = append(clients, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	for i := range clients {
+		if err = s.loadAssociations(ctx, &clients[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return clients, total, nil
+}
+
+// loadAssociations reads the client's redirect URLs and allowed scopes.
+func (s *ClientStore) loadAssociations(ctx context.Context, c *store.Client) error {
+	var err error
+	if c.RedirectURLs, err = s.getRedirectURLs(ctx, c.ID); err != nil {
+		return err
+	}
+
+	if c.AllowedScopes, err = s.getScopes(ctx, c.ID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *ClientStore) getRedirectURLs(ctx context.Context, id int) ([]string, error) {
 	rows, err := s.db.QueryContext(
 		ctx,
 		`SELECT url FROM redirect_url WHERE client_id = ?`,
-		c.ID,
+		id,
 	)
 	if err != nil {
-		return store.Client{}, err
+		return nil, err
 	}
 	defer rows.Close()
 
+	var urls []string
 	for rows.Next() {
 		var row string
 		if err := rows.Scan(&row); err != nil {
-			return store.Client{}, err
+			return nil, err
 		}
-		c.RedirectURLs = append(c.RedirectURLs, row)
+		urls = append(urls, row)
 	}
 
 	if err = rows.Err(); err != nil {
-		return store.Client{}, err
+		return nil, err
 	}
 
-	return c, nil
+	return urls, nil
+}
+
+func (s *ClientStore) getScopes(ctx context.Context, id int) ([]string, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT scope FROM client_scope WHERE client_id = ?`,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scopes []string
+	for rows.Next() {
+		var row string
+		if err := rows.Scan(&row); err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, row)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return scopes, nil
 }
 
 func (s *ClientStore) Create(ctx context.Context, c store.Client) (int, error) {
@@ -62,9 +198,24 @@ func (s *ClientStore) Create(ctx context.Context, c store.Client) (int, error) {
 	defer tx.Commit()
 
 	res, err := tx.Exec(
-		`INSERT INTO client (client_id, client_secret) VALUES (?, ?)`,
+		`INSERT INTO client (
+			client_id, name, client_type, secret_hash, require_pkce, resource_server,
+			token_endpoint_auth_method, grant_types, response_types, access_token_lifespan,
+			refresh_token_lifespan, id_token_lifespan, auth_code_lifespan
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		c.ClientID,
-		c.ClientSecret,
+		c.Name,
+		c.Type,
+		c.SecretHash,
+		c.RequirePKCE,
+		c.ResourceServer,
+		c.TokenEndpointAuthMethod,
+		strings.Join(c.GrantTypes, " "),
+		strings.Join(c.ResponseTypes, " "),
+		c.TokenLifespans.AccessToken,
+		c.TokenLifespans.RefreshToken,
+		c.TokenLifespans.IDToken,
+		c.TokenLifespans.AuthCode,
 	)
 	if err != nil {
 		tx.Rollback()
@@ -77,17 +228,221 @@ func (s *ClientStore) Create(ctx context.Context, c store.Client) (int, error) {
 		return 0, err
 	}
 
+	if err = insertAssociations(tx, int(id), c); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// insertAssociations inserts the client's redirect URLs and allowed scopes.
+func insertAssociations(tx *sql.Tx, id int, c store.Client) error {
 	for _, url := range c.RedirectURLs {
-		_, err = tx.Exec(
+		_, err := tx.Exec(
 			`INSERT INTO redirect_url (client_id, url) VALUES (?, ?)`,
 			id,
 			url,
 		)
 		if err != nil {
+			return err
+		}
+	}
+
+	for _, scope := range c.AllowedScopes {
+		_, err := tx.Exec(
+			`INSERT INTO client_scope (client_id, scope) VALUES (?, ?)`,
+			id,
+			scope,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *ClientStore) Update(ctx context.Context, c store.Client) error {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	var id int
+	err = tx.QueryRowContext(
+		ctx,
+		`UPDATE client
+		SET name = ?, require_pkce = ?, resource_server = ?, token_endpoint_auth_method = ?,
+			grant_types = ?, response_types = ?, access_token_lifespan = ?,
+			refresh_token_lifespan = ?, id_token_lifespan = ?, auth_code_lifespan = ?
+		WHERE client_id = ? RETURNING id`,
+		c.Name,
+		c.RequirePKCE,
+		c.ResourceServer,
+		c.TokenEndpointAuthMethod,
+		strings.Join(c.GrantTypes, " "),
+		strings.Join(c.ResponseTypes, " "),
+		c.TokenLifespans.AccessToken,
+		c.TokenLifespans.RefreshToken,
+		c.TokenLifespans.IDToken,
+		c.TokenLifespans.AuthCode,
+		c.ClientID,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		tx.Rollback()
+		return errors.New("client not found")
+	} else if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, query := range []string{
+		`DELETE FROM redirect_url WHERE client_id = ?`,
+		`DELETE FROM client_scope WHERE client_id = ?`,
+	} {
+		if _, err = tx.ExecContext(ctx, query, id); err != nil {
 			tx.Rollback()
-			return 0, err
+			return err
 		}
 	}
 
-	return int(id), nil
+	if err = insertAssociations(tx, id, c); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *ClientStore) SetDisabled(ctx context.Context, clientID string, disabled bool) error {
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE client SET disabled = ? WHERE client_id = ?`,
+		disabled,
+		clientID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return requireClientRow(res)
+}
+
+func (s *ClientStore) Delete(ctx context.Context, clientID string) error {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	var id int
+	err = tx.QueryRowContext(
+		ctx,
+		`DELETE FROM client WHERE client_id = ? RETURNING id`,
+		clientID,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		tx.Rollback()
+		return errors.New("client not found")
+	} else if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Foreign keys are not enforced, so dependent rows are deleted explicitly. Redirect URLs
+	// and scopes reference the row ID while grants reference the public client_id.
+	for _, q := range []struct {
+		query string
+		arg   interface{}
+	}{
+		{`DELETE FROM redirect_url WHERE client_id = ?`, id},
+		{`DELETE FROM client_scope WHERE client_id = ?`, id},
+		{`DELETE FROM auth_code WHERE client_id = ?`, clientID},
+		{`DELETE FROM refresh_token WHERE client_id = ?`, clientID},
+		{`DELETE FROM consent_grant WHERE client_id = ?`, clientID},
+		{`DELETE FROM consent_request WHERE client_id = ?`, clientID},
+	} {
+		if _, err = tx.ExecContext(ctx, q.query, q.arg); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *ClientStore) ListSecretHashes(ctx context.Context) (map[string]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT client_id, secret_hash FROM client`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hashes := make(map[string]string)
+	for rows.Next() {
+		var clientID, hash string
+		if err := rows.Scan(&clientID, &hash); err != nil {
+			return nil, err
+		}
+		hashes[clientID] = hash
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return hashes, nil
+}
+
+func (s *ClientStore) UpdateSecretHash(ctx context.Context, clientID, secretHash string) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE client SET secret_hash = ? WHERE client_id = ?`,
+		secretHash,
+		clientID,
+	)
+	return err
+}
+
+func (s *ClientStore) RotateSecret(ctx context.Context, clientID, secretHash string, previousExpiresAt time.Time) error {
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE client
+		SET previous_secret_hash = secret_hash, previous_secret_expires_at = ?, secret_hash = ?
+		WHERE client_id = ?`,
+		previousExpiresAt.UTC(),
+		secretHash,
+		clientID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return requireClientRow(res)
+}
+
+func (s *ClientStore) ExpirePreviousSecret(ctx context.Context, clientID string) error {
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE client SET previous_secret_hash = NULL, previous_secret_expires_at = NULL
+		WHERE client_id = ?`,
+		clientID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return requireClientRow(res)
+}
+
+// requireClientRow returns an error if the statement did not affect a client.
+func requireClientRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New("client not found")
+	}
+
+	return nil
 }
@@ -0,0 +1,193 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/mattmeyers/heimdall/store"
+)
+
+var _ store.ConsentStore = (*ConsentStore)(nil)
+
+type ConsentStore struct {
+	db *sql.DB
+}
+
+func NewConsentStore(db *sql.DB) (*ConsentStore, error) {
+	return &ConsentStore{db: db}, nil
+}
+
+func (s *ConsentStore) GetGrant(ctx context.Context, userID int, clientID string) (store.ConsentGrant, error) {
+	grants, err := s.listGrants(
+		ctx,
+		`SELECT client_id, scope, created_at FROM consent_grant
+		WHERE user_id = ? AND client_id = ? ORDER BY id`,
+		userID,
+		clientID,
+	)
+	if err != nil {
+		return store.ConsentGrant{}, err
+	} else if len(grants) == 0 {
+		return store.ConsentGrant{UserID: userID, ClientID: clientID}, nil
+	}
+
+	grants[0].UserID = userID
+	return grants[0], nil
+}
+
+func (s *ConsentStore) ListGrants(ctx context.Context, userID int) ([]store.ConsentGrant, error) {
+	grants, err := s.listGrants(
+		ctx,
+		`SELECT client_id, scope, created_at FROM consent_grant
+		WHERE user_id = ? ORDER BY client_id, id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range grants {
+		grants[i].UserID = userID
+	}
+
+	return grants, nil
+}
+
+// listGrants runs a query selecting the client_id, scope and created_at of grant rows
+// ordered by client, and combines the rows of each client into a single grant.
+func (s *ConsentStore) listGrants(ctx context.Context, q string, args ...interface{}) ([]store.ConsentGrant, error) {
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grants []store.ConsentGrant
+	for rows.Next() {
+		var clientID, scope string
+		var createdAt time.Time
+		if err := rows.Scan(&clientID, &scope, &createdAt); err != nil {
+			return nil, err
+		}
+
+		if n := len(grants); n > 0 && grants[n-1].ClientID == clientID {
+			grants[n-1].Scopes = append(grants[n-1].Scopes, scope)
+			continue
+		}
+
+		grants = append(grants, store.ConsentGrant{
+			ClientID:  clientID,
+			Scopes:    []string{scope},
+			CreatedAt: createdAt,
+		})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return grants, nil
+}
+
+func (s *ConsentStore) Grant(ctx context.Context, g store.ConsentGrant) error {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Commit()
+
+	for _, scope := range g.Scopes {
+		_, err = tx.Exec(
+			`INSERT INTO consent_grant (user_id, client_id, scope, created_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT (user_id, client_id, scope) DO NOTHING`,
+			g.UserID,
+			g.ClientID,
+			scope,
+			g.CreatedAt.UTC(),
+		)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *ConsentStore) RevokeGrant(ctx context.Context, userID int, clientID string) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`DELETE FROM consent_grant WHERE user_id = ? AND client_id = ?`,
+		userID,
+		clientID,
+	)
+	return err
+}
+
+func (s *ConsentStore) InsertRequest(ctx context.Context, r store.ConsentRequest) (int, error) {
+	res, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO consent_request (challenge, user_id, client_id, redirect_url, state, scope,
+			nonce, code_challenge, code_challenge_method, auth_time, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.Challenge,
+		r.UserID,
+		r.ClientID,
+		r.RedirectURL,
+		r.State,
+		r.Scope,
+		r.Nonce,
+		r.CodeChallenge,
+		r.CodeChallengeMethod,
+		r.AuthTime.UTC(),
+		r.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (s *ConsentStore) ConsumeRequest(ctx context.Context, challenge string) (store.ConsentRequest, error) {
+	var r store.ConsentRequest
+	err := s.db.
+		QueryRowContext(
+			ctx,
+			`DELETE FROM consent_request WHERE challenge = ?
+			RETURNING id, challenge, user_id, client_id, redirect_url, state, scope, nonce,
+				code_challenge, code_challenge_method, auth_time, created_at`,
+			challenge,
+		).
+		Scan(
+			&r.ID,
+			&r.Challenge,
+			&r.UserID,
+			&r.ClientID,
+			&r.RedirectURL,
+			&r.State,
+			&r.Scope,
+			&r.Nonce,
+			&r.CodeChallenge,
+			&r.CodeChallengeMethod,
+			&r.AuthTime,
+			&r.CreatedAt,
+		)
+	if err != nil {
+		return store.ConsentRequest{}, errors.New("consent request not found")
+	}
+
+	return r, nil
+}
+
+func (s *ConsentStore) DeleteExpiredRequests(ctx context.Context, before time.Time) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM consent_request WHERE created_at < ?`, before.UTC())
+	return err
+}
@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/mattmeyers/heimdall/store"
+)
+
+var _ store.RefreshTokenStore = (*RefreshTokenStore)(nil)
+
+type RefreshTokenStore struct {
+	db *sql.DB
+}
+
+func NewRefreshTokenStore(db *sql.DB) (*RefreshTokenStore, error) {
+	return &RefreshTokenStore{db: db}, nil
+}
+
+func (s *RefreshTokenStore) GetByToken(ctx context.Context, token string) (store.RefreshToken, error) {
+	q := `SELECT id, token, family_id, user_id, client_id, scope, auth_time, created_at, expires_at,
+		used_at IS NOT NULL, revoked_at IS NOT NULL
+		FROM refresh_token WHERE token = ?`
+
+	var t store.RefreshToken
+	err := s.db.QueryRowContext(ctx, q, token).Scan(
+		&t.ID,
+		&t.Token,
+		&t.FamilyID,
+		&t.UserID,
+		&t.ClientID,
+		&t.Scope,
+		&t.AuthTime,
+		&t.CreatedAt,
+		&t.ExpiresAt,
+		&t.Used,
+		&t.Revoked,
+	)
+	if err != nil {
+		return store.RefreshToken{}, errors.New("refresh token not found")
+	}
+
+	return t, nil
+}
+
+func (s *RefreshTokenStore) Insert(ctx context.Context, t store.RefreshToken) (int, error) {
+	q := `INSERT INTO refresh_token (token, family_id, user_id, client_id, scope, auth_time,
+		created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	res, err := s.db.ExecContext(
+		ctx,
+		q,
+		t.Token,
+		t.FamilyID,
+		t.UserID,
+		t.ClientID,
+		t.Scope,
+		t.AuthTime.UTC(),
+		t.CreatedAt.UTC(),
+		t.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (s *RefreshTokenStore) MarkUsed(ctx context.Context, id int) error {
+	q := `UPDATE refresh_token SET used_at = ? WHERE id = ? AND used_at IS NULL`
+
+	res, err := s.db.ExecContext(ctx, q, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	} else if n == 0 {
+		return store.ErrRefreshTokenUsed
+	}
+
+	return nil
+}
+
+func (s *RefreshTokenStore) RevokeFamily(ctx context.Context, familyID string) error {
+	q := `UPDATE refresh_token SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL`
+
+	_, err := s.db.ExecContext(ctx, q, time.Now().UTC(), familyID)
+	return err
+}
+
+func (s *RefreshTokenStore) RevokeClient(ctx context.Context, userID int, clientID string) error {
+	q := `UPDATE refresh_token SET revoked_at = ? WHERE user_id = ? AND client_id = ? AND revoked_at IS NULL`
+
+	_, err := s.db.ExecContext(ctx, q, time.Now().UTC(), userID, clientID)
+	return err
+}
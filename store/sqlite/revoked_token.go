@@ -0,0 +1,45 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/mattmeyers/heimdall/store"
+)
+
+var _ store.RevokedTokenStore = (*RevokedTokenStore)(nil)
+
+type RevokedTokenStore struct {
+	db *sql.DB
+}
+
+func NewRevokedTokenStore(db *sql.DB) (*RevokedTokenStore, error) {
+	return &RevokedTokenStore{db: db}, nil
+}
+
+func (s *RevokedTokenStore) Insert(ctx context.Context, t store.RevokedToken) error {
+	q := `INSERT INTO revoked_token (token_id, revoked_at, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (token_id) DO NOTHING`
+
+	_, err := s.db.ExecContext(ctx, q, t.TokenID, t.RevokedAt.UTC(), t.ExpiresAt.UTC())
+	return err
+}
+
+func (s *RevokedTokenStore) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	q := `SELECT EXISTS (SELECT 1 FROM revoked_token WHERE token_id = ?)`
+
+	var revoked bool
+	if err := s.db.QueryRowContext(ctx, q, tokenID).Scan(&revoked); err != nil {
+		return false, err
+	}
+
+	return revoked, nil
+}
+
+func (s *RevokedTokenStore) DeleteExpired(ctx context.Context, before time.Time) error {
+	q := `DELETE FROM revoked_token WHERE expires_at < ?`
+
+	_, err := s.db.ExecContext(ctx, q, before.UTC())
+	return err
+}
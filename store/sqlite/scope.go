@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mattmeyers/heimdall/store"
+)
+
+var _ store.ScopeStore = (*ScopeStore)(nil)
+
+type ScopeStore struct {
+	db *sql.DB
+}
+
+func NewScopeStore(db *sql.DB) (*ScopeStore, error) {
+	return &ScopeStore{db: db}, nil
+}
+
+func (s *ScopeStore) List(ctx context.Context) ([]store.Scope, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, name, description, requires_consent FROM scope ORDER BY id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scopes []store.Scope
+	for rows.Next() {
+		var sc store.Scope
+		if err := rows.Scan(&sc.ID, &sc.Name, &sc.Description, &sc.RequiresConsent); err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, sc)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return scopes, nil
+}
+
+func (s *ScopeStore) Create(ctx context.Context, sc store.Scope) (int, error) {
+	res, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO scope (name, description, requires_consent) VALUES (?, ?, ?)`,
+		sc.Name,
+		sc.Description,
+		sc.RequiresConsent,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/mattmeyers/heimdall/store"
+)
+
+var _ store.SigningKeyStore = (*SigningKeyStore)(nil)
+
+type SigningKeyStore struct {
+	db *sql.DB
+}
+
+func NewSigningKeyStore(db *sql.DB) (*SigningKeyStore, error) {
+	return &SigningKeyStore{db: db}, nil
+}
+
+func (s *SigningKeyStore) List(ctx context.Context) ([]store.SigningKey, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, state, private_key, created_at, activated_at, retired_at
+		FROM signing_key ORDER BY created_at`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []store.SigningKey
+	for rows.Next() {
+		var k store.SigningKey
+		var activatedAt, retiredAt sql.NullTime
+		err := rows.Scan(&k.ID, &k.State, &k.PrivateKey, &k.CreatedAt, &activatedAt, &retiredAt)
+		if err != nil {
+			return nil, err
+		}
+		k.ActivatedAt = activatedAt.Time
+		k.RetiredAt = retiredAt.Time
+		keys = append(keys, k)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (s *SigningKeyStore) Insert(ctx context.Context, k store.SigningKey) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO signing_key (id, state, private_key, created_at, activated_at, retired_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		k.ID,
+		k.State,
+		k.PrivateKey,
+		k.CreatedAt.UTC(),
+		nullTime(k.ActivatedAt),
+		nullTime(k.RetiredAt),
+	)
+	return err
+}
+
+func (s *SigningKeyStore) Update(ctx context.Context, k store.SigningKey) error {
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE signing_key SET state = ?, activated_at = ?, retired_at = ? WHERE id = ?`,
+		k.State,
+		nullTime(k.ActivatedAt),
+		nullTime(k.RetiredAt),
+		k.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New("signing key not found")
+	}
+
+	return nil
+}
+
+func (s *SigningKeyStore) Delete(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM signing_key WHERE id = ?`, id)
+	return err
+}
+
+// nullTime converts the zero time to NULL.
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/mattmeyers/heimdall/store"
 	"modernc.org/sqlite"
@@ -19,34 +20,38 @@ func NewUserStore(db *sql.DB) (*UserStore, error) {
 	return &UserStore{db: db}, nil
 }
 
-func (s *UserStore) GetByID(ctx context.Context, id int) (store.User, error) {
-	q := `SELECT id, email, hash FROM user WHERE id = ?`
+// userColumns are the user columns read by scanUser.
+const userColumns = `id, email, email_verified, name, hash, tokens_revoked_at`
 
+func scanUser(row scanner) (store.User, error) {
 	var u store.User
-	err := s.db.QueryRowContext(ctx, q, id).Scan(&u.ID, &u.Email, &u.Hash)
+	var tokensRevokedAt sql.NullTime
+	err := row.Scan(&u.ID, &u.Email, &u.EmailVerified, &u.Name, &u.Hash, &tokensRevokedAt)
 	if err != nil {
 		return store.User{}, errors.New("user not found")
 	}
 
+	u.TokensRevokedAt = tokensRevokedAt.Time
+
 	return u, nil
 }
 
-func (s *UserStore) GetByEmail(ctx context.Context, email string) (store.User, error) {
-	q := `SELECT id, email, hash FROM user WHERE email = ?`
+func (s *UserStore) GetByID(ctx context.Context, id int) (store.User, error) {
+	q := `SELECT ` + userColumns + ` FROM user WHERE id = ?`
 
-	var u store.User
-	err := s.db.QueryRowContext(ctx, q, email).Scan(&u.ID, &u.Email, &u.Hash)
-	if err != nil {
-		return store.User{}, errors.New("user not found")
-	}
+	return scanUser(s.db.QueryRowContext(ctx, q, id))
+}
 
-	return u, nil
+func (s *UserStore) GetByEmail(ctx context.Context, email string) (store.User, error) {
+	q := `SELECT ` + userColumns + ` FROM user WHERE email = ?`
+
+	return scanUser(s.db.QueryRowContext(ctx, q, email))
 }
 
 func (s *UserStore) Create(ctx context.Context, u store.User) (int, error) {
-	q := `INSERT INTO user (email, hash) VALUES (?, ?)`
+	q := `INSERT INTO user (email, email_verified, name, hash) VALUES (?, ?, ?, ?)`
 
-	res, err := s.db.ExecContext(ctx, q, u.Email, u.Hash)
+	res, err := s.db.ExecContext(ctx, q, u.Email, u.EmailVerified, u.Name, u.Hash)
 
 	var sqlErr *sqlite.Error
 	if errors.As(err, &sqlErr) && sqlErr.Code() == 2067 {
@@ -62,3 +67,21 @@ func (s *UserStore) Create(ctx context.Context, u store.User) (int, error) {
 
 	return int(id), nil
 }
+
+func (s *UserStore) RevokeTokens(ctx context.Context, id int, at time.Time) error {
+	q := `UPDATE user SET tokens_revoked_at = ? WHERE id = ?`
+
+	res, err := s.db.ExecContext(ctx, q, at.UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New("user not found")
+	}
+
+	return nil
+}
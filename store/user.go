@@ -1,15 +1,25 @@
 package store
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type User struct {
-	ID    int    `json:"id"`
-	Email string `json:"email"`
-	Hash  string `json:"hash"`
+	ID            int    `json:"id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Hash          string `json:"hash"`
+	// TokensRevokedAt is the last time the user revoked the tokens they obtained by signing
+	// in directly. Tokens issued at or before this time are rejected.
+	TokensRevokedAt time.Time `json:"-"`
 }
 
 type UserStore interface {
 	GetByID(ctx context.Context, id int) (User, error)
 	GetByEmail(ctx context.Context, email string) (User, error)
 	Create(ctx context.Context, u User) (int, error)
+	// RevokeTokens sets the user's TokensRevokedAt.
+	RevokeTokens(ctx context.Context, id int, at time.Time) error
 }
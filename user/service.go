@@ -19,13 +19,13 @@ func (s *Service) Get(ctx context.Context, id int) (store.User, error) {
 	return s.userStore.GetByID(ctx, id)
 }
 
-func (s *Service) Register(ctx context.Context, email, password string) (int, error) {
+func (s *Service) Register(ctx context.Context, email, password, name string) (int, error) {
 	hash, err := crypto.GetPasswordHash(password, crypto.DefaultParams)
 	if err != nil {
 		return 0, err
 	}
 
-	u := store.User{Email: email, Hash: hash}
+	u := store.User{Email: email, Name: name, Hash: hash}
 	id, err := s.userStore.Create(ctx, u)
 	if err != nil {
 		return 0, err